@@ -0,0 +1,203 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/peterbourgon/ff/v4"
+
+	wireguard2 "github.com/xbnz/wireguard-config-generator/pkg/wireguard"
+)
+
+// errConfigsDiffer is returned by diff --exit-code when there are changes.
+var errConfigsDiffer = errors.New("configurations differ")
+
+// fileDiff is the set of semantic changes for one configuration file. Status
+// is "added" or "removed" when the file only exists on one side, and
+// "skipped" with the reason in Error when a directory holds a .conf file that
+// cannot be parsed, such as one written by the template format.
+type fileDiff struct {
+	File    string              `json:"file"`
+	Status  string              `json:"status"`
+	Changes []wireguard2.Change `json:"changes,omitempty"`
+	Error   string              `json:"error,omitempty"`
+}
+
+func newDiffCommand(stdout io.Writer) *ff.Command {
+	fs := ff.NewFlagSet("diff")
+	jsonOutput := fs.BoolLong("json", "print changes as JSON")
+	exitCode := fs.BoolLong(
+		"exit-code",
+		"exit with status 1 if the configurations differ",
+	)
+
+	return &ff.Command{
+		Name:      "diff",
+		Usage:     "wireguard-config-generator diff [FLAGS] <OLD> <NEW>",
+		ShortHelp: "compare two configuration files or directories semantically",
+		Flags:     fs,
+		Exec: func(_ context.Context, args []string) error {
+			if len(args) != 2 {
+				return errors.New("diff needs exactly two paths")
+			}
+
+			diffs, err := diffPaths(args[0], args[1])
+			if err != nil {
+				return err
+			}
+
+			if *jsonOutput {
+				err = writeDiffJSON(stdout, diffs)
+			} else {
+				writeDiffText(stdout, diffs)
+			}
+
+			if err == nil && *exitCode && hasChanges(diffs) {
+				return errConfigsDiffer
+			}
+			return err
+		},
+	}
+}
+
+// diffPaths compares two .conf files, or every .conf file of two directories
+// matched by file name. Files whose configurations are semantically equal
+// are left out of the result. Unlike two files given directly, files of a
+// directory that cannot be parsed are reported as skipped instead of failing
+// the whole comparison.
+func diffPaths(oldPath string, newPath string) ([]fileDiff, error) {
+	oldInfo, err := os.Stat(oldPath)
+	if err != nil {
+		return nil, fmt.Errorf("stat %s: %w", oldPath, err)
+	}
+
+	newInfo, err := os.Stat(newPath)
+	if err != nil {
+		return nil, fmt.Errorf("stat %s: %w", newPath, err)
+	}
+
+	switch {
+	case !oldInfo.IsDir() && !newInfo.IsDir():
+		diff, err := diffFiles(newPath, oldPath, newPath)
+		if err != nil {
+			return nil, err
+		}
+		if len(diff.Changes) == 0 {
+			return nil, nil
+		}
+		return []fileDiff{diff}, nil
+	case oldInfo.IsDir() && newInfo.IsDir():
+		return diffDirs(oldPath, newPath)
+	default:
+		return nil, errors.New("diff needs two files or two directories")
+	}
+}
+
+func diffDirs(oldDir string, newDir string) ([]fileDiff, error) {
+	oldFiles, err := confFiles([]string{oldDir})
+	if err != nil {
+		return nil, err
+	}
+
+	newFiles, err := confFiles([]string{newDir})
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(oldFiles)+len(newFiles))
+	for _, file := range slices.Concat(oldFiles, newFiles) {
+		names = append(names, filepath.Base(file))
+	}
+	slices.Sort(names)
+	names = slices.Compact(names)
+
+	var diffs []fileDiff
+
+	for _, name := range names {
+		oldFile := filepath.Join(oldDir, name)
+		newFile := filepath.Join(newDir, name)
+
+		switch {
+		case !slices.Contains(oldFiles, oldFile):
+			diffs = append(diffs, fileDiff{File: name, Status: "added"})
+		case !slices.Contains(newFiles, newFile):
+			diffs = append(diffs, fileDiff{File: name, Status: "removed"})
+		default:
+			diff, err := diffFiles(name, oldFile, newFile)
+			if err != nil {
+				diffs = append(diffs, fileDiff{
+					File:   name,
+					Status: "skipped",
+					Error:  err.Error(),
+				})
+				continue
+			}
+			if len(diff.Changes) > 0 {
+				diffs = append(diffs, diff)
+			}
+		}
+	}
+
+	return diffs, nil
+}
+
+func diffFiles(name string, oldFile string, newFile string) (fileDiff, error) {
+	oldConfig, err := readConfFile(oldFile)
+	if err != nil {
+		return fileDiff{}, fmt.Errorf("%s: %w", oldFile, err)
+	}
+
+	newConfig, err := readConfFile(newFile)
+	if err != nil {
+		return fileDiff{}, fmt.Errorf("%s: %w", newFile, err)
+	}
+
+	return fileDiff{
+		File:    name,
+		Status:  "changed",
+		Changes: wireguard2.Diff(oldConfig, newConfig),
+	}, nil
+}
+
+// hasChanges reports whether any of the files was added, removed or changed.
+func hasChanges(diffs []fileDiff) bool {
+	return slices.ContainsFunc(diffs, func(diff fileDiff) bool {
+		return diff.Status != "skipped"
+	})
+}
+
+func writeDiffText(w io.Writer, diffs []fileDiff) {
+	for _, diff := range diffs {
+		switch diff.Status {
+		case "changed":
+			for _, change := range diff.Changes {
+				fmt.Fprintf(w, "%s: %s\n", diff.File, change)
+			}
+		case "skipped":
+			fmt.Fprintf(w, "%s: skipped: %s\n", diff.File, diff.Error)
+		default:
+			fmt.Fprintf(w, "%s: file %s\n", diff.File, diff.Status)
+		}
+	}
+}
+
+func writeDiffJSON(w io.Writer, diffs []fileDiff) error {
+	if diffs == nil {
+		diffs = []fileDiff{}
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+
+	if err := encoder.Encode(diffs); err != nil {
+		return fmt.Errorf("encode diff: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	wireguard2 "github.com/xbnz/wireguard-config-generator/pkg/wireguard"
+)
+
+func TestDiffPaths(t *testing.T) {
+	t.Parallel()
+
+	oldDir := filepath.Join("testdata", "diff", "old")
+	newDir := filepath.Join("testdata", "diff", "new")
+
+	t.Run("directories are matched by file name", func(t *testing.T) {
+		t.Parallel()
+
+		diffs, err := diffPaths(oldDir, newDir)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var out bytes.Buffer
+		writeDiffText(&out, diffs)
+
+		assert.Equal(
+			t,
+			"nordvpn_0.conf: DNS removed 8.8.8.8\n"+
+				"nordvpn_0.conf: peer qIhtTW9K4iXWFo5Q4dOPdXg8/xubXr9yEGoN55D8xnA=: "+
+				"Endpoint changed 1.1.1.1:51820 -> 1.1.1.2:51820\n"+
+				"nordvpn_1.conf: file removed\n"+
+				"nordvpn_2.conf: file added\n"+
+				"notes.conf: skipped: "+filepath.Join(oldDir, "notes.conf")+
+				": line 2: key outside of a section\n",
+			out.String(),
+		)
+	})
+
+	t.Run("exit code reports differences", func(t *testing.T) {
+		t.Parallel()
+
+		run := func(oldPath string, newPath string) error {
+			return newDiffCommand(io.Discard).ParseAndRun(
+				t.Context(),
+				[]string{"--exit-code", oldPath, newPath},
+			)
+		}
+
+		assert.ErrorIs(t, run(oldDir, newDir), errConfigsDiffer)
+		assert.NoError(t, run(
+			filepath.Join(oldDir, "nordvpn_0.conf"),
+			filepath.Join(newDir, "nordvpn_2.conf"),
+		))
+	})
+
+	t.Run("identical files produce no output", func(t *testing.T) {
+		t.Parallel()
+
+		diffs, err := diffPaths(
+			filepath.Join(oldDir, "nordvpn_0.conf"),
+			filepath.Join(newDir, "nordvpn_2.conf"),
+		)
+
+		assert.NoError(t, err)
+		assert.Empty(t, diffs)
+	})
+
+	t.Run("json output can be decoded", func(t *testing.T) {
+		t.Parallel()
+
+		diffs, err := diffPaths(
+			filepath.Join(oldDir, "nordvpn_0.conf"),
+			filepath.Join(newDir, "nordvpn_0.conf"),
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var out bytes.Buffer
+		if err := writeDiffJSON(&out, diffs); err != nil {
+			t.Fatal(err)
+		}
+
+		var decoded []fileDiff
+		if err := json.Unmarshal(out.Bytes(), &decoded); err != nil {
+			t.Fatal(err)
+		}
+
+		assert.Len(t, decoded, 1)
+		assert.Equal(t, "changed", decoded[0].Status)
+		assert.Contains(t, decoded[0].Changes, wireguard2.Change{
+			Kind:  wireguard2.ChangeRemoved,
+			Field: "DNS",
+			Old:   "8.8.8.8",
+		})
+	})
+
+	t.Run("a file and a directory cannot be compared", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := diffPaths(filepath.Join(oldDir, "nordvpn_0.conf"), newDir)
+		assert.ErrorContains(t, err, "two files or two directories")
+	})
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/peterbourgon/ff/v4"
+
+	wireguard2 "github.com/xbnz/wireguard-config-generator/pkg/wireguard"
+)
+
+func newLintCommand(stdout io.Writer) *ff.Command {
+	return &ff.Command{
+		Name:      "lint",
+		Usage:     "wireguard-config-generator lint <PATH> [<PATH>...]",
+		ShortHelp: "check existing WireGuard configuration files for mistakes",
+		Flags:     ff.NewFlagSet("lint"),
+		Exec: func(_ context.Context, args []string) error {
+			return lint(stdout, args)
+		},
+	}
+}
+
+// lint parses every .conf file in paths, directories included, and prints
+// the findings of Configuration.Validate for each of them. It fails when a
+// file cannot be parsed or has at least one error.
+func lint(w io.Writer, paths []string) error {
+	files, err := confFiles(paths)
+	if err != nil {
+		return err
+	}
+
+	failed := 0
+
+	for _, file := range files {
+		config, err := readConfFile(file)
+		if err != nil {
+			fmt.Fprintf(w, "%s: error: %v\n", file, err)
+			failed++
+			continue
+		}
+
+		findings := config.Validate()
+		for _, finding := range findings {
+			fmt.Fprintf(w, "%s: %s\n", file, finding)
+		}
+
+		if wireguard2.HasErrors(findings) {
+			failed++
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d files have errors", failed, len(files))
+	}
+
+	return nil
+}
+
+// confFiles expands the given paths into a list of .conf files. Files are
+// taken as given, directories contribute the .conf files directly inside them.
+func confFiles(paths []string) ([]string, error) {
+	if len(paths) == 0 {
+		return nil, errors.New("no configuration files given")
+	}
+
+	var files []string
+
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, fmt.Errorf("stat %s: %w", path, err)
+		}
+
+		if !info.IsDir() {
+			files = append(files, path)
+			continue
+		}
+
+		matches, err := filepath.Glob(filepath.Join(path, "*.conf"))
+		if err != nil {
+			return nil, fmt.Errorf("list %s: %w", path, err)
+		}
+
+		files = append(files, matches...)
+	}
+
+	return files, nil
+}
+
+func readConfFile(path string) (wireguard2.Configuration, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return wireguard2.Configuration{}, fmt.Errorf("open: %w", err)
+	}
+	defer file.Close()
+
+	return wireguard2.ParseINI(file)
+}
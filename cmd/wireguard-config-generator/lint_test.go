@@ -0,0 +1,58 @@
+package main
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLint(t *testing.T) {
+	t.Parallel()
+
+	lintDir := filepath.Join("testdata", "lint")
+
+	t.Run("a valid file passes without findings", func(t *testing.T) {
+		t.Parallel()
+
+		var out bytes.Buffer
+		err := lint(&out, []string{filepath.Join(lintDir, "valid.conf")})
+
+		assert.NoError(t, err)
+		assert.Empty(t, out.String())
+	})
+
+	t.Run("findings are reported per file", func(t *testing.T) {
+		t.Parallel()
+
+		var out bytes.Buffer
+		err := lint(&out, []string{lintDir})
+
+		assert.ErrorContains(t, err, "2 of 3 files have errors")
+		assert.Contains(
+			t,
+			out.String(),
+			filepath.Join(lintDir, "invalid.conf")+": error: [key] private key",
+		)
+		assert.Contains(
+			t,
+			out.String(),
+			filepath.Join(lintDir, "invalid.conf")+
+				": warning: [address-family] peer 0: AllowedIPs ::/0",
+		)
+		assert.Contains(
+			t,
+			out.String(),
+			filepath.Join(lintDir, "malformed.conf")+
+				": error: line 3: unknown section",
+		)
+	})
+
+	t.Run("no paths is an error", func(t *testing.T) {
+		t.Parallel()
+
+		err := lint(&bytes.Buffer{}, nil)
+		assert.ErrorContains(t, err, "no configuration files given")
+	})
+}
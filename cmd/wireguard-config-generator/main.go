@@ -1,24 +1,30 @@
 package main
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
 	"io"
 	"log"
+	"math/rand/v2"
 	"net/http"
+	"net/netip"
 	"os"
 	"os/signal"
 	"path/filepath"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/go-playground/validator/v10"
 	"github.com/peterbourgon/ff/v4"
 	"github.com/peterbourgon/ff/v4/ffhelp"
+	"github.com/samber/lo"
 
 	"github.com/xbnz/wireguard-config-generator/internal/enums"
 	wireguard2 "github.com/xbnz/wireguard-config-generator/pkg/wireguard"
+	"github.com/xbnz/wireguard-config-generator/pkg/wireguard/format"
 	nordvpn2 "github.com/xbnz/wireguard-config-generator/pkg/wireguard/providers/nordvpn"
 
 	"github.com/xbnz/wireguard-config-generator/internal/cidr"
@@ -34,7 +40,44 @@ type Config struct {
 	DNS                 string `ff:"long=dns, default=1.1.1.1, usage=Comma separated list of DNS servers to use for the WireGuard interface"                                    validate:"required"`
 	AllowedIPs          string `ff:"long=allowed-ips, default=0.0.0.0/0, usage=Comma separated list of allowed IPs for the WireGuard peer"                                      validate:"required"`
 	PersistentKeepalive string `ff:"long=persistent-keepalive, default=25, usage=Persistent keepalive interval in seconds"                                                      validate:"required,numeric,min=1,max=65535"`
-	OutputDir           string `ff:"long=output-dir, usage=Directory to output WireGuard configuration files to"                                                                validate:"required"`
+	OutputDir           string `ff:"long=output-dir, usage=Directory to output WireGuard configuration files to"                                                                validate:"required_without=Stdout"`
+	Format              string `ff:"long=format, default=ini, usage=Comma separated list of output formats to write side by side (see README for all formats)"                  validate:"required"`
+	NetworkdKeyFile     bool   `ff:"long=networkd-key-file, usage=Write the private key of networkd configs to a separate credential file"                                      validate:"-"`
+	NetworkdKeyDir      string `ff:"long=networkd-key-dir, default=/etc/systemd/network, usage=Directory networkd configs expect credential files in"                           validate:"required"`
+	NetworkdRouteTable  string `ff:"long=networkd-route-table, usage=Routing table for networkd routes with fwmark policy rules; full tunnels default to 51820"                 validate:"omitempty,number"`
+	OpenWrtBatch        bool   `ff:"long=openwrt-batch, usage=Render OpenWrt configs as uci batch commands instead of /etc/config fragments"                                    validate:"-"`
+	OpenWrtFirewall     bool   `ff:"long=openwrt-firewall, usage=Add a masquerading firewall zone to OpenWrt configs"                                                           validate:"-"`
+	OpenWrtPrefix       string `ff:"long=openwrt-prefix, usage=Prefix for OpenWrt interface and section names"                                                                  validate:"omitempty"`
+	MikroTikRouteTable  bool   `ff:"long=mikrotik-routing-table, usage=Add a routing table with routes for the AllowedIPs to MikroTik scripts"                                  validate:"-"`
+	MikroTikMangle      bool   `ff:"long=mikrotik-mangle, usage=Add a mangle rule marking traffic from an address list to MikroTik scripts"                                     validate:"-"`
+	QRLevel             string `ff:"long=qr-level, default=medium, usage=Error correction level of QR codes (low/medium/high/highest)"                                          validate:"oneof=low medium high highest"`
+	QRSize              string `ff:"long=qr-size, default=512, usage=Width and height of QR code images in pixels"                                                              validate:"required,number"`
+	Stdout              bool   `ff:"long=stdout, usage=Print the selected formats to stdout instead of writing files"                                                           validate:"-"`
+	GluetunCompose      bool   `ff:"long=gluetun-compose, usage=Render Gluetun configs as docker-compose environment blocks instead of .env files"                              validate:"-"`
+	GluetunBundle       bool   `ff:"long=gluetun-bundle, usage=Write one Gluetun environment for the provider plus a servers.json of all servers"                               validate:"-"`
+	KubernetesSingle    bool   `ff:"long=kubernetes-single, usage=Write all Kubernetes Secrets into one multi-document secrets.yaml"                                            validate:"-"`
+	KubernetesName      string `ff:"long=kubernetes-name-template, default=wireguard-{{.Name}}, usage=Go template naming each Kubernetes Secret"                                validate:"required"`
+	KubernetesNamespace string `ff:"long=kubernetes-namespace, usage=Namespace of the Kubernetes Secrets"                                                                       validate:"omitempty"`
+	KubernetesKustomize bool   `ff:"long=kubernetes-kustomization, usage=Add a kustomization.yaml listing the Kubernetes manifests"                                             validate:"-"`
+	DocumentBatch       bool   `ff:"long=document-batch, usage=Write the json and yaml formats as one document with server metadata"                                            validate:"-"`
+	WireproxyBind       string `ff:"long=wireproxy-bind-address, default=127.0.0.1, usage=Local address wireproxy listeners bind to"                                            validate:"required,ip"`
+	WireproxySocksPort  string `ff:"long=wireproxy-socks-port, default=1080, usage=SOCKS5 port of the first wireproxy config (0 disables); later configs count up"              validate:"omitempty,number"`
+	WireproxyHTTPPort   string `ff:"long=wireproxy-http-port, usage=HTTP proxy port of the first wireproxy config; later configs count up"                                      validate:"omitempty,number"`
+	WireproxyTunnels    string `ff:"long=wireproxy-tunnels, usage=Comma separated list of port=host:port TCP tunnels for wireproxy configs"                                     validate:"omitempty"`
+	ProxyGroupType      string `ff:"long=proxy-group-type, default=url-test, usage=Type of the sing-box and Clash proxy group (url-test/fallback)"                              validate:"omitempty,oneof=url-test fallback"`
+	ProxyGroupName      string `ff:"long=proxy-group-name, default=wireguard, usage=Name of the proxy group and Xray balancer"                                                  validate:"omitempty"`
+	ProxyGroupURL       string `ff:"long=proxy-group-url, default=https://www.gstatic.com/generate_204, usage=URL probed to test the proxy group servers"                       validate:"omitempty,url"`
+	ProxyGroupInterval  string `ff:"long=proxy-group-interval, default=5m, usage=Time between two probes of the proxy group"                                                    validate:"omitempty"`
+	NixOSKeyDir         string `ff:"long=nixos-key-dir, default=/run/secrets/wireguard, usage=Directory NixOS modules expect private and preshared key files in"                validate:"required"`
+	NixOSAutostart      bool   `ff:"long=nixos-autostart, usage=Start the tunnels of NixOS modules at boot"                                                                     validate:"-"`
+	NetplanRouteTable   string `ff:"long=netplan-route-table, usage=Routing table for Netplan routes; default routes are left out without one"                                  validate:"omitempty,number"`
+	VyOSRouteTable      string `ff:"long=vyos-route-table, usage=Routing table for static routes of VyOS configs through the tunnel"                                            validate:"omitempty,number"`
+	AmneziaWGParams     string `ff:"long=amneziawg-parameters, usage=Comma separated list of key=value AmneziaWG parameters used instead of generated ones"                     validate:"omitempty"`
+	AmneziaWGHeaders    bool   `ff:"long=amneziawg-headers, usage=Also generate AmneziaWG S1 S2 and H1-H4 (needs an AmneziaWG server set up with them)"                         validate:"-"`
+	Archive             string `ff:"long=archive, usage=Write all output files and a manifest.json into one archive (zip/tar.gz) instead"                                       validate:"omitempty,oneof=zip tar.gz"`
+	Template            string `ff:"long=template, usage=Go text/template file rendered by the template format"                                                                 validate:"omitempty,file"`
+	Index               bool   `ff:"long=index, usage=Also write an index.html and README.md listing the configs by location"                                                   validate:"-"`
+	IndexQR             bool   `ff:"long=index-qr, usage=Embed a QR code of each config into index.html (needs --index)"                                                        validate:"-"`
 }
 
 type App struct {
@@ -44,27 +87,44 @@ type App struct {
 	ConfigGenerator wireguard2.ConfigGenerator
 	HttpClient      *http.Client
 	Validator       *validator.Validate
+	Formatters      *format.Registry
+	Stdout          io.Writer
 }
 
-func newApp(ctx context.Context) (*App, error) {
+func newRootCommand(stdout io.Writer) (*ff.Command, error) {
 	var cfg Config
 	fs := ff.NewFlagSet("wireguard-config-generator")
 	if err := fs.AddStruct(&cfg); err != nil {
 		return nil, fmt.Errorf("add struct flags: %w", err)
 	}
 
-	if err := ff.Parse(
-		fs,
-		os.Args[1:],
-		ff.WithEnvVarPrefix("WIREGUARD_CONFIG_GENERATOR"),
-	); err != nil {
-		if errors.Is(err, ff.ErrHelp) {
-			fmt.Fprint(os.Stderr, ffhelp.Flags(fs))
-			return nil, err
-		}
-		return nil, fmt.Errorf("parse flags: %w", err)
+	servers, err := newServersCommand(stdout)
+	if err != nil {
+		return nil, err
 	}
 
+	return &ff.Command{
+		Name:  "wireguard-config-generator",
+		Usage: "wireguard-config-generator [FLAGS] [SUBCOMMAND]",
+		Flags: fs,
+		Subcommands: []*ff.Command{
+			newLintCommand(stdout),
+			newDiffCommand(stdout),
+			servers,
+		},
+		Exec: func(ctx context.Context, _ []string) error {
+			app, err := newApp(ctx, cfg)
+			if err != nil {
+				return fmt.Errorf("create app: %w", err)
+			}
+			app.Stdout = stdout
+
+			return run(app)
+		},
+	}, nil
+}
+
+func newApp(ctx context.Context, cfg Config) (*App, error) {
 	validate := validator.New(validator.WithRequiredStructEnabled())
 
 	err := validate.StructCtx(ctx, cfg)
@@ -83,18 +143,11 @@ func newApp(ctx context.Context) (*App, error) {
 		return nil, fmt.Errorf("ensure config values for provider: %w", err)
 	}
 
-	transport := &http.Transport{
-		MaxIdleConns:          100,
-		MaxIdleConnsPerHost:   10,
-		IdleConnTimeout:       90 * time.Second,
-		TLSHandshakeTimeout:   10 * time.Second,
-		ResponseHeaderTimeout: 10 * time.Second,
-		ExpectContinueTimeout: 1 * time.Second,
-	}
+	client := newHTTPClient()
 
-	client := &http.Client{
-		Timeout:   30 * time.Second,
-		Transport: transport,
+	formatters, err := newFormatterRegistry(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("create formatter registry: %w", err)
 	}
 
 	var configGeneratorImpl wireguard2.ConfigGenerator
@@ -122,26 +175,272 @@ func newApp(ctx context.Context) (*App, error) {
 		ConfigGenerator: configGeneratorImpl,
 		HttpClient:      client,
 		Validator:       validate,
+		Formatters:      formatters,
 	}, nil
 }
 
+// newHTTPClient returns the client used to talk to provider APIs.
+func newHTTPClient() *http.Client {
+	transport := &http.Transport{
+		MaxIdleConns:          100,
+		MaxIdleConnsPerHost:   10,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ResponseHeaderTimeout: 10 * time.Second,
+		ExpectContinueTimeout: 1 * time.Second,
+	}
+
+	return &http.Client{
+		Timeout:   30 * time.Second,
+		Transport: transport,
+	}
+}
+
+// newFormatterRegistry registers every output format the --format flag can
+// select.
+func newFormatterRegistry(cfg Config) (*format.Registry, error) {
+	var qrSize int
+	if cfg.QRSize != "" {
+		var err error
+		qrSize, err = strconv.Atoi(cfg.QRSize)
+		if err != nil {
+			return nil, fmt.Errorf("parse QR code size: %w", err)
+		}
+	}
+
+	var routeTable uint64
+	if cfg.NetworkdRouteTable != "" {
+		var err error
+		routeTable, err = strconv.ParseUint(cfg.NetworkdRouteTable, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("parse networkd route table: %w", err)
+		}
+	}
+
+	var netplanRouteTable uint64
+	if cfg.NetplanRouteTable != "" {
+		var err error
+		netplanRouteTable, err = strconv.ParseUint(
+			cfg.NetplanRouteTable,
+			10,
+			32,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("parse Netplan route table: %w", err)
+		}
+	}
+
+	var vyosRouteTable uint64
+	if cfg.VyOSRouteTable != "" {
+		var err error
+		vyosRouteTable, err = strconv.ParseUint(cfg.VyOSRouteTable, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("parse VyOS route table: %w", err)
+		}
+	}
+
+	wireproxy, err := newWireproxyOptions(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	amneziaWG, err := newAmneziaWGOptions(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	var templateText []byte
+	if cfg.Template != "" {
+		templateText, err = os.ReadFile(cfg.Template)
+		if err != nil {
+			return nil, fmt.Errorf("read template: %w", err)
+		}
+	}
+
+	proxyGroup := format.ProxyGroupOptions{
+		Type: cfg.ProxyGroupType,
+		Name: cfg.ProxyGroupName,
+		URL:  cfg.ProxyGroupURL,
+	}
+	if cfg.ProxyGroupInterval != "" {
+		proxyGroup.Interval, err = time.ParseDuration(cfg.ProxyGroupInterval)
+		if err != nil {
+			return nil, fmt.Errorf("parse proxy group interval: %w", err)
+		}
+	}
+
+	return format.NewRegistry(
+		format.NewINI(),
+		format.NewIPC(),
+		format.NewNetworkManager(format.NetworkManagerOptions{
+			Warnf: log.Printf,
+		}),
+		format.NewNetworkd(format.NetworkdOptions{
+			KeyFile:    cfg.NetworkdKeyFile,
+			KeyDir:     cfg.NetworkdKeyDir,
+			RouteTable: uint32(routeTable),
+		}),
+		format.NewOpenWrt(format.OpenWrtOptions{
+			Batch:    cfg.OpenWrtBatch,
+			Firewall: cfg.OpenWrtFirewall,
+			Prefix:   cfg.OpenWrtPrefix,
+		}),
+		format.NewMikroTik(format.MikroTikOptions{
+			RoutingTable: cfg.MikroTikRouteTable,
+			Mangle:       cfg.MikroTikMangle,
+		}),
+		format.NewOPNsense(),
+		format.NewPfSense(),
+		format.NewQR(format.QROptions{
+			Level: cfg.QRLevel,
+			Size:  qrSize,
+			Warnf: log.Printf,
+		}),
+		format.NewGluetun(format.GluetunOptions{
+			Compose:   cfg.GluetunCompose,
+			Bundle:    cfg.GluetunBundle,
+			Provider:  cfg.Provider,
+			Timestamp: time.Now().Unix(),
+		}),
+		format.NewKubernetes(format.KubernetesOptions{
+			Single:        cfg.KubernetesSingle,
+			NameTemplate:  cfg.KubernetesName,
+			Namespace:     cfg.KubernetesNamespace,
+			Kustomization: cfg.KubernetesKustomize,
+		}),
+		format.NewJSON(format.DocumentOptions{Batch: cfg.DocumentBatch}),
+		format.NewYAML(format.DocumentOptions{Batch: cfg.DocumentBatch}),
+		format.NewWireproxy(wireproxy),
+		format.NewSingBox(proxyGroup),
+		format.NewClash(proxyGroup),
+		format.NewXray(proxyGroup),
+		format.NewNixOS(format.NixOSOptions{
+			KeyDir:    cfg.NixOSKeyDir,
+			Autostart: cfg.NixOSAutostart,
+		}),
+		format.NewNetplan(format.NetplanOptions{
+			RouteTable: uint32(netplanRouteTable),
+			Warnf: func(message string, v ...any) {
+				log.Printf(message+"; set --netplan-route-table", v...)
+			},
+		}),
+		format.NewVyOS(format.VyOSOptions{RouteTable: uint32(vyosRouteTable)}),
+		format.NewOpenBSD(),
+		format.NewFreeBSD(),
+		format.NewAmneziaWG(amneziaWG),
+		format.NewTemplate(format.TemplateOptions{
+			Path: cfg.Template,
+			Text: string(templateText),
+		}),
+	)
+}
+
+func newAmneziaWGOptions(cfg Config) (format.AmneziaWGOptions, error) {
+	options := format.AmneziaWGOptions{
+		Headers: cfg.AmneziaWGHeaders,
+		Rand:    rand.New(rand.NewPCG(rand.Uint64(), rand.Uint64())),
+	}
+
+	if cfg.AmneziaWGParams == "" {
+		return options, nil
+	}
+
+	params, err := wireguard2.ParseAmneziaWG(cfg.AmneziaWGParams)
+	if err != nil {
+		return options, fmt.Errorf("parse AmneziaWG parameters: %w", err)
+	}
+	if problems := params.Problems(); len(problems) > 0 {
+		return options, fmt.Errorf(
+			"invalid AmneziaWG parameters: %s",
+			strings.Join(problems, "; "),
+		)
+	}
+	options.Parameters = &params
+
+	return options, nil
+}
+
+func newWireproxyOptions(cfg Config) (format.WireproxyOptions, error) {
+	var options format.WireproxyOptions
+
+	if cfg.WireproxyBind != "" {
+		bind, err := netip.ParseAddr(cfg.WireproxyBind)
+		if err != nil {
+			return options, fmt.Errorf("parse wireproxy bind address: %w", err)
+		}
+		options.BindAddress = bind
+	}
+
+	for _, port := range []struct {
+		name  string
+		value string
+		port  *uint16
+	}{
+		{"SOCKS5", cfg.WireproxySocksPort, &options.SocksPort},
+		{"HTTP", cfg.WireproxyHTTPPort, &options.HTTPPort},
+	} {
+		if port.value == "" {
+			continue
+		}
+
+		parsed, err := strconv.ParseUint(port.value, 10, 16)
+		if err != nil {
+			return options, fmt.Errorf(
+				"parse wireproxy %s port: %w",
+				port.name,
+				err,
+			)
+		}
+		*port.port = uint16(parsed)
+	}
+
+	for value := range strings.SplitSeq(cfg.WireproxyTunnels, ",") {
+		value = strings.TrimSpace(value)
+		if value == "" {
+			continue
+		}
+
+		tunnel, err := format.ParseWireproxyTunnel(value)
+		if err != nil {
+			return options, err
+		}
+		options.Tunnels = append(options.Tunnels, tunnel)
+	}
+
+	return options, nil
+}
+
 func main() {
 	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
 	defer cancel()
 
-	app, err := newApp(ctx)
+	root, err := newRootCommand(os.Stdout)
 	if err != nil {
 		log.Printf("Error creating app: %v", err)
 		os.Exit(1)
 	}
 
-	if err := run(app); err != nil {
+	err = root.ParseAndRun(
+		ctx,
+		os.Args[1:],
+		ff.WithEnvVarPrefix("WIREGUARD_CONFIG_GENERATOR"),
+	)
+	if errors.Is(err, ff.ErrHelp) {
+		fmt.Fprint(os.Stderr, ffhelp.Command(root.GetSelected()))
+		return
+	}
+
+	if err != nil {
 		log.Printf("Error: %v", err)
 		os.Exit(1)
 	}
 }
 
 func run(app *App) error {
+	if err := ensureIndexFlags(app.Config); err != nil {
+		return err
+	}
+
 	interfaceAddresses, err := cidr.ParseSeparated(
 		app.Config.InterfaceAddresses,
 		",",
@@ -174,37 +473,315 @@ func run(app *App) error {
 		return fmt.Errorf("list configs: %w", err)
 	}
 
+	if err := validateConfigs(configs); err != nil {
+		return err
+	}
+
+	formatters, err := app.Formatters.Select(
+		lo.Map(
+			strings.Split(app.Config.Format, ","),
+			func(name string, _ int) string {
+				return strings.TrimSpace(name)
+			},
+		),
+	)
+	if err != nil {
+		return fmt.Errorf("select output formats: %w", err)
+	}
+
+	items := make([]format.Item, 0, len(configs))
 	for i, config := range configs {
-		ini, err := config.ToINIFormat()
+		item := format.NewItem(
+			fmt.Sprintf("%s_%d", app.Config.Provider, i),
+			config.Configuration,
+		)
+		item.Server = config.Server
+
+		items = append(items, item)
+	}
+
+	if app.Config.Stdout && app.Config.Archive == "" {
+		return printOutputs(app.Stdout, formatters, items)
+	}
+
+	var catalog *format.Catalog
+	if app.Config.Index {
+		catalog, err = newCatalog(app)
 		if err != nil {
-			return fmt.Errorf("convert config to INI format: %w", err)
+			return err
 		}
+	}
+
+	outputs, err := renderOutputs(formatters, items, catalog)
+	if err != nil {
+		return err
+	}
 
-		absolutePath, err := filepath.Abs(app.Config.OutputDir)
+	switch {
+	case app.Config.Archive != "" && app.Config.Stdout:
+		return writeArchive(app.Stdout, app.Config.Archive, items, outputs)
+	case app.Config.Archive != "":
+		return writeArchiveFile(
+			app.Config.OutputDir,
+			app.Config.Provider+"."+app.Config.Archive,
+			app.Config.Archive,
+			items,
+			outputs,
+		)
+	default:
+		return writeOutputs(app.Config.OutputDir, outputs)
+	}
+}
+
+// newCatalog returns the catalog of the run. Inline QR codes are rendered
+// with the settings of the qr format.
+func newCatalog(app *App) (*format.Catalog, error) {
+	options := format.CatalogOptions{Generated: time.Now()}
 
+	if app.Config.IndexQR {
+		formatters, err := app.Formatters.Select([]string{"qr"})
 		if err != nil {
-			return fmt.Errorf("get absolute path of output directory: %w", err)
+			return nil, fmt.Errorf("select QR code format: %w", err)
 		}
-
-		if err := os.MkdirAll(absolutePath, 0755); err != nil {
-			return fmt.Errorf("create output directory: %w", err)
+		qr, ok := formatters[0].(*format.QR)
+		if !ok {
+			return nil, fmt.Errorf("qr format is a %T", formatters[0])
 		}
+		options.QR = qr
+	}
 
-		fileName := filepath.Join(
-			absolutePath,
-			fmt.Sprintf("%s_%d.conf", app.Config.Provider, i),
-		)
+	return format.NewCatalog(options), nil
+}
+
+// renderOutputs renders the items with every formatter, followed by the
+// catalog when one is given. Everything is rendered before the first file
+// is written, so formats that would overwrite each other's files are caught
+// up front.
+func renderOutputs(
+	formatters []format.Formatter,
+	items []format.Item,
+	catalog *format.Catalog,
+) ([]format.Output, error) {
+	var outputs []format.Output
+	owners := map[string]string{}
+
+	add := func(name string, files []format.File) error {
+		for _, file := range files {
+			if owner, ok := owners[file.Name]; ok {
+				return fmt.Errorf(
+					"formats %s and %s both write %s",
+					owner,
+					name,
+					file.Name,
+				)
+			}
+			owners[file.Name] = name
+
+			outputs = append(outputs, format.Output{Format: name, File: file})
+		}
+		return nil
+	}
 
-		file, err := os.Create(fileName)
+	for _, formatter := range formatters {
+		rendered, err := formatter.FormatBatch(items)
 		if err != nil {
-			return fmt.Errorf("create output file: %w", err)
+			return nil, fmt.Errorf(
+				"convert configs to %s format: %w",
+				formatter.Name(),
+				err,
+			)
+		}
+
+		if err := add(formatter.Name(), rendered); err != nil {
+			return nil, err
+		}
+	}
+
+	if catalog == nil {
+		return outputs, nil
+	}
+
+	rendered, err := catalog.Files(items, outputs)
+	if err != nil {
+		return nil, fmt.Errorf("render index: %w", err)
+	}
+	if err := add("index", rendered); err != nil {
+		return nil, err
+	}
+
+	return outputs, nil
+}
+
+// writeOutputs writes the rendered files below outputDir.
+func writeOutputs(outputDir string, outputs []format.Output) error {
+	absolutePath, err := filepath.Abs(outputDir)
+
+	if err != nil {
+		return fmt.Errorf("get absolute path of output directory: %w", err)
+	}
+
+	for _, output := range outputs {
+		if err := writeFile(absolutePath, output.File); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// writeArchive writes the rendered files together with a manifest into a
+// single archive of the given kind.
+func writeArchive(
+	w io.Writer,
+	kind string,
+	items []format.Item,
+	outputs []format.Output,
+) error {
+	modTime, err := archiveModTime()
+	if err != nil {
+		return err
+	}
+
+	if err := format.WriteArchive(
+		w,
+		kind,
+		items,
+		outputs,
+		modTime,
+	); err != nil {
+		return fmt.Errorf("write %s archive: %w", kind, err)
+	}
+
+	return nil
+}
+
+// sourceDateEpoch names the environment variable fixing the modification
+// time of archive entries.
+const sourceDateEpoch = "SOURCE_DATE_EPOCH"
+
+// archiveModTime returns the modification time of archive entries. Like
+// other reproducible builds, it honors SOURCE_DATE_EPOCH so that the same
+// outputs make the same archive, and falls back to the current time.
+func archiveModTime() (time.Time, error) {
+	epoch, ok := os.LookupEnv(sourceDateEpoch)
+	if !ok || epoch == "" {
+		return time.Now(), nil
+	}
+
+	seconds, err := strconv.ParseInt(epoch, 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("parse %s: %w", sourceDateEpoch, err)
+	}
+
+	return time.Unix(seconds, 0).UTC(), nil
+}
+
+// writeArchiveFile writes the archive as name below outputDir. It holds the
+// private keys of every configuration, so it keeps the default mode.
+func writeArchiveFile(
+	outputDir string,
+	name string,
+	kind string,
+	items []format.Item,
+	outputs []format.Output,
+) error {
+	absolutePath, err := filepath.Abs(outputDir)
+	if err != nil {
+		return fmt.Errorf("get absolute path of output directory: %w", err)
+	}
+
+	var archive bytes.Buffer
+	if err := writeArchive(&archive, kind, items, outputs); err != nil {
+		return err
+	}
+
+	return writeFile(absolutePath, format.File{
+		Name:    name,
+		Content: archive.Bytes(),
+	})
+}
+
+// printOutputs renders the items with every formatter and prints each file
+// under a header naming it. Formatters with a terminal rendering, such as QR
+// codes, print that instead of their files.
+func printOutputs(
+	w io.Writer,
+	formatters []format.Formatter,
+	items []format.Item,
+) error {
+	for _, formatter := range formatters {
+		terminal, ok := formatter.(format.TerminalFormatter)
+		if !ok {
+			files, err := formatter.FormatBatch(items)
+			if err != nil {
+				return fmt.Errorf(
+					"convert configs to %s format: %w",
+					formatter.Name(),
+					err,
+				)
+			}
+
+			for _, file := range files {
+				fmt.Fprintf(w, "# %s\n%s\n", file.Name, file.Content)
+			}
+
+			continue
+		}
+
+		for _, item := range items {
+			content, err := terminal.FormatTerminal(item)
+			if errors.Is(err, format.ErrQRTooLarge) {
+				log.Printf(
+					"skipping %s for %s: %v",
+					formatter.Name(),
+					item.Name,
+					err,
+				)
+				continue
+			}
+			if err != nil {
+				return fmt.Errorf(
+					"convert %s to %s format: %w",
+					item.Name,
+					formatter.Name(),
+					err,
+				)
+			}
+
+			fmt.Fprintf(w, "# %s\n%s\n", item.Name, content)
+		}
+	}
+
+	return nil
+}
+
+// validateConfigs logs every warning and error found in the generated
+// configurations and refuses to continue if any of them has an error.
+func validateConfigs(configs []wireguard2.ServerConfiguration) error {
+	invalid := 0
+
+	for i, config := range configs {
+		findings := config.Validate()
+
+		for _, finding := range findings {
+			if finding.Severity >= wireguard2.SeverityWarning {
+				log.Printf("config %d: %s", i, finding)
+			}
 		}
 
-		if err := writeContent(file, ini); err != nil {
-			return fmt.Errorf("write content to file: %w", err)
+		if wireguard2.HasErrors(findings) {
+			invalid++
 		}
 	}
 
+	if invalid > 0 {
+		return fmt.Errorf(
+			"refusing to write configs: %d of %d have errors",
+			invalid,
+			len(configs),
+		)
+	}
+
 	return nil
 }
 
@@ -222,6 +799,56 @@ func ensureConfigValuesForProvider(
 	return nil
 }
 
+// ensureIndexFlags rejects catalog flags that would have no effect. Printed
+// files have no directory for the catalog to sit in unless they are
+// archived.
+func ensureIndexFlags(cfg Config) error {
+	if cfg.IndexQR && !cfg.Index {
+		return errors.New("--index-qr needs --index")
+	}
+	if cfg.Index && cfg.Stdout && cfg.Archive == "" {
+		return errors.New("--index with --stdout needs --archive")
+	}
+
+	return nil
+}
+
+// writeFile writes a rendered file below the output directory, creating any
+// missing directories on the way.
+func writeFile(outputDir string, file format.File) error {
+	fileName := filepath.Join(outputDir, file.Name)
+
+	if err := os.MkdirAll(filepath.Dir(fileName), 0755); err != nil {
+		return fmt.Errorf("create output directory: %w", err)
+	}
+
+	mode := file.Mode
+	if mode == 0 {
+		mode = format.DefaultFileMode
+	}
+
+	handle, err := os.OpenFile(
+		fileName,
+		os.O_WRONLY|os.O_CREATE|os.O_TRUNC,
+		mode,
+	)
+	if err != nil {
+		return fmt.Errorf("create output file: %w", err)
+	}
+
+	// Files that already exist keep their permissions on open.
+	if err := handle.Chmod(mode); err != nil {
+		handle.Close()
+		return fmt.Errorf("set output file permissions: %w", err)
+	}
+
+	if err := writeContent(handle, string(file.Content)); err != nil {
+		return fmt.Errorf("write content to file: %w", err)
+	}
+
+	return nil
+}
+
 func writeContent(closer io.WriteCloser, content string) error {
 	defer closer.Close()
 	_, err := closer.Write([]byte(content))
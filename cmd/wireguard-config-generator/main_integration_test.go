@@ -1,6 +1,8 @@
 package main
 
 import (
+	"archive/zip"
+	"bytes"
 	"context"
 	"log"
 	"net/netip"
@@ -9,10 +11,12 @@ import (
 	"testing"
 
 	"github.com/go-playground/validator/v10"
+	"github.com/samber/lo"
 	"github.com/stretchr/testify/assert"
 
 	"github.com/xbnz/wireguard-config-generator/internal/enums"
 	wireguard2 "github.com/xbnz/wireguard-config-generator/pkg/wireguard"
+	"github.com/xbnz/wireguard-config-generator/pkg/wireguard/format"
 )
 
 type SpyConfigGenerator struct {
@@ -32,15 +36,22 @@ func (s *SpyConfigGenerator) List(
 	allowedIPs []netip.Prefix,
 	persistentKeepalive uint16,
 	dns []netip.Addr,
-) ([]wireguard2.Configuration, error) {
+) ([]wireguard2.ServerConfiguration, error) {
 	s.ListCalledTimes++
-	return s.ListFunc(
+	configs, err := s.ListFunc(
 		ctx,
 		interfaceAddresses,
 		allowedIPs,
 		persistentKeepalive,
 		dns,
 	)
+
+	return lo.Map(
+		configs,
+		func(config wireguard2.Configuration, _ int) wireguard2.ServerConfiguration {
+			return wireguard2.ServerConfiguration{Configuration: config}
+		},
+	), err
 }
 
 func TestMain_Run(t *testing.T) {
@@ -49,12 +60,12 @@ func TestMain_Run(t *testing.T) {
 		spyConfigGenerator.ListFunc = func(ctx context.Context, interfaceAddresses []netip.Prefix, allowedIPs []netip.Prefix, persistentKeepalive uint16, dns []netip.Addr) ([]wireguard2.Configuration, error) {
 			return []wireguard2.Configuration{
 				wireguard2.NewConfiguration(
-					"private_key",
+					"OEvHuuMpALNf7ZZkzUSGbT8vkj89aHrhLyqZlIn4rPU=",
 					interfaceAddresses,
 					dns,
 					[]wireguard2.PeerConfig{
 						wireguard2.NewPeerConfig(
-							"public_key",
+							"qIhtTW9K4iXWFo5Q4dOPdXg8/xubXr9yEGoN55D8xnA=",
 							netip.MustParseAddrPort("1.1.1.1:51820"),
 							[]netip.Prefix{netip.MustParsePrefix("0.0.0.0/0")},
 							25,
@@ -71,11 +82,13 @@ func TestMain_Run(t *testing.T) {
 		app.Config.AllowedIPs = "10.0.0.0/24,10.0.1.0/24"
 		app.Config.DNS = "8.8.8.8, 1.1.1.1"
 		app.Config.PersistentKeepalive = "25"
+		app.Config.Format = "ini"
 
 		app.Provider = enums.NopProvider()
 		app.Ctx = context.Background()
 		app.Validator = validator.New(validator.WithRequiredStructEnabled())
 		app.ConfigGenerator = spyConfigGenerator
+		app.Formatters = newTestFormatterRegistry(t, app.Config)
 
 		err := run(app)
 		if err != nil {
@@ -86,6 +99,12 @@ func TestMain_Run(t *testing.T) {
 		assert.FileExists(t, filepath.Join(tempDir, "test_0.conf"))
 		assert.Equal(t, 1, spyConfigGenerator.ListCalledTimes)
 
+		info, err := os.Stat(filepath.Join(tempDir, "test_0.conf"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, format.DefaultFileMode, info.Mode().Perm())
+
 		fileContent, err := os.ReadFile(filepath.Join(tempDir, "test_0.conf"))
 		if err != nil {
 			log.Fatal(err)
@@ -97,4 +116,410 @@ func TestMain_Run(t *testing.T) {
 		}
 		assert.Equal(t, goldenContent, fileContent)
 	})
+	t.Run("it refuses to write configs with errors", func(t *testing.T) {
+		spyConfigGenerator := &SpyConfigGenerator{}
+		spyConfigGenerator.ListFunc = func(ctx context.Context, interfaceAddresses []netip.Prefix, allowedIPs []netip.Prefix, persistentKeepalive uint16, dns []netip.Addr) ([]wireguard2.Configuration, error) {
+			return []wireguard2.Configuration{
+				wireguard2.NewConfiguration(
+					"private_key",
+					interfaceAddresses,
+					dns,
+					[]wireguard2.PeerConfig{
+						wireguard2.NewPeerConfig(
+							"public_key",
+							netip.MustParseAddrPort("1.1.1.1:51820"),
+							allowedIPs,
+							persistentKeepalive,
+						),
+					},
+				),
+			}, nil
+		}
+		tempDir := t.TempDir()
+		app := &App{}
+		app.Config.Provider = "test"
+		app.Config.OutputDir = tempDir
+		app.Config.InterfaceAddresses = "10.0.0.0/24"
+		app.Config.AllowedIPs = "0.0.0.0/0"
+		app.Config.DNS = "1.1.1.1"
+		app.Config.PersistentKeepalive = "25"
+		app.Config.Format = "ini"
+
+		app.Provider = enums.NopProvider()
+		app.Ctx = context.Background()
+		app.ConfigGenerator = spyConfigGenerator
+		app.Formatters = newTestFormatterRegistry(t, app.Config)
+
+		err := run(app)
+
+		assert.ErrorContains(t, err, "refusing to write configs")
+		assert.NoFileExists(t, filepath.Join(tempDir, "test_0.conf"))
+	})
+	t.Run("it writes every selected format side by side", func(t *testing.T) {
+		spyConfigGenerator := &SpyConfigGenerator{}
+		spyConfigGenerator.ListFunc = func(ctx context.Context, interfaceAddresses []netip.Prefix, allowedIPs []netip.Prefix, persistentKeepalive uint16, dns []netip.Addr) ([]wireguard2.Configuration, error) {
+			return []wireguard2.Configuration{
+				wireguard2.NewConfiguration(
+					"OEvHuuMpALNf7ZZkzUSGbT8vkj89aHrhLyqZlIn4rPU=",
+					interfaceAddresses,
+					dns,
+					[]wireguard2.PeerConfig{
+						wireguard2.NewPeerConfig(
+							"qIhtTW9K4iXWFo5Q4dOPdXg8/xubXr9yEGoN55D8xnA=",
+							netip.MustParseAddrPort("1.1.1.1:51820"),
+							[]netip.Prefix{netip.MustParsePrefix("0.0.0.0/0")},
+							25,
+						),
+					},
+				),
+			}, nil
+		}
+		tempDir := t.TempDir()
+		app := &App{}
+		app.Config.Provider = "test"
+		app.Config.OutputDir = tempDir
+		app.Config.InterfaceAddresses = "10.0.0.0/24,10.0.1.0/24"
+		app.Config.AllowedIPs = "10.0.0.0/24,10.0.1.0/24"
+		app.Config.DNS = "8.8.8.8, 1.1.1.1"
+		app.Config.PersistentKeepalive = "25"
+		app.Config.Format = "ini, ipc"
+
+		app.Provider = enums.NopProvider()
+		app.Ctx = context.Background()
+		app.ConfigGenerator = spyConfigGenerator
+		app.Formatters = newTestFormatterRegistry(t, app.Config)
+
+		err := run(app)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, name := range []string{"test_0.conf", "test_0.ipc"} {
+			fileContent, err := os.ReadFile(filepath.Join(tempDir, name))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			goldenContent, err := os.ReadFile(
+				filepath.Join("testdata", name+".golden"),
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			assert.Equal(t, string(goldenContent), string(fileContent))
+		}
+	})
+
+	t.Run("stdout prints files and terminal renderings", func(t *testing.T) {
+		spyConfigGenerator := &SpyConfigGenerator{}
+		spyConfigGenerator.ListFunc = func(ctx context.Context, interfaceAddresses []netip.Prefix, allowedIPs []netip.Prefix, persistentKeepalive uint16, dns []netip.Addr) ([]wireguard2.Configuration, error) {
+			return []wireguard2.Configuration{
+				wireguard2.NewConfiguration(
+					"OEvHuuMpALNf7ZZkzUSGbT8vkj89aHrhLyqZlIn4rPU=",
+					interfaceAddresses,
+					dns,
+					[]wireguard2.PeerConfig{
+						wireguard2.NewPeerConfig(
+							"qIhtTW9K4iXWFo5Q4dOPdXg8/xubXr9yEGoN55D8xnA=",
+							netip.MustParseAddrPort("1.1.1.1:51820"),
+							allowedIPs,
+							25,
+						),
+					},
+				),
+			}, nil
+		}
+		var stdout bytes.Buffer
+		app := &App{}
+		app.Config.Provider = "test"
+		app.Config.Stdout = true
+		app.Config.InterfaceAddresses = "10.0.0.0/24"
+		app.Config.AllowedIPs = "0.0.0.0/0"
+		app.Config.DNS = "1.1.1.1"
+		app.Config.PersistentKeepalive = "25"
+		app.Config.Format = "ini,qr"
+
+		app.Provider = enums.NopProvider()
+		app.Ctx = context.Background()
+		app.ConfigGenerator = spyConfigGenerator
+		app.Formatters = newTestFormatterRegistry(t, app.Config)
+		app.Stdout = &stdout
+
+		err := run(app)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		assert.Contains(t, stdout.String(), "# test_0.conf\n[Interface]\n")
+		assert.Contains(t, stdout.String(), "# test_0\n█")
+		assert.NotContains(t, stdout.String(), "PNG")
+	})
+
+	t.Run("formats writing the same file are rejected", func(t *testing.T) {
+		spyConfigGenerator := &SpyConfigGenerator{}
+		spyConfigGenerator.ListFunc = func(ctx context.Context, interfaceAddresses []netip.Prefix, allowedIPs []netip.Prefix, persistentKeepalive uint16, dns []netip.Addr) ([]wireguard2.Configuration, error) {
+			return []wireguard2.Configuration{
+				wireguard2.NewConfiguration(
+					"OEvHuuMpALNf7ZZkzUSGbT8vkj89aHrhLyqZlIn4rPU=",
+					interfaceAddresses,
+					dns,
+					[]wireguard2.PeerConfig{
+						wireguard2.NewPeerConfig(
+							"qIhtTW9K4iXWFo5Q4dOPdXg8/xubXr9yEGoN55D8xnA=",
+							netip.MustParseAddrPort("1.1.1.1:51820"),
+							allowedIPs,
+							persistentKeepalive,
+						),
+					},
+				),
+			}, nil
+		}
+		tempDir := t.TempDir()
+		app := &App{}
+		app.Config.Provider = "test"
+		app.Config.OutputDir = tempDir
+		app.Config.InterfaceAddresses = "10.0.0.2/32"
+		app.Config.AllowedIPs = "0.0.0.0/0"
+		app.Config.DNS = "1.1.1.1"
+		app.Config.PersistentKeepalive = "25"
+		app.Config.Format = "ipc,ini,wg-quick"
+
+		app.Provider = enums.NopProvider()
+		app.Ctx = context.Background()
+		app.ConfigGenerator = spyConfigGenerator
+		app.Formatters = newTestFormatterRegistry(t, app.Config)
+
+		ini, _ := app.Formatters.Get("ini")
+		err := app.Formatters.Register(renamedFormatter{ini, "wg-quick"})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = run(app)
+
+		assert.ErrorContains(
+			t,
+			err,
+			"formats ini and wg-quick both write test_0.conf",
+		)
+
+		entries, err := os.ReadDir(tempDir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Empty(t, entries)
+	})
+
+	t.Run("archive bundles the formats with a manifest", func(t *testing.T) {
+		spyConfigGenerator := &SpyConfigGenerator{}
+		spyConfigGenerator.ListFunc = func(ctx context.Context, interfaceAddresses []netip.Prefix, allowedIPs []netip.Prefix, persistentKeepalive uint16, dns []netip.Addr) ([]wireguard2.Configuration, error) {
+			return []wireguard2.Configuration{
+				wireguard2.NewConfiguration(
+					"OEvHuuMpALNf7ZZkzUSGbT8vkj89aHrhLyqZlIn4rPU=",
+					interfaceAddresses,
+					dns,
+					[]wireguard2.PeerConfig{
+						wireguard2.NewPeerConfig(
+							"qIhtTW9K4iXWFo5Q4dOPdXg8/xubXr9yEGoN55D8xnA=",
+							netip.MustParseAddrPort("1.1.1.1:51820"),
+							allowedIPs,
+							persistentKeepalive,
+						),
+					},
+				),
+			}, nil
+		}
+		tempDir := t.TempDir()
+		app := &App{}
+		app.Config.Provider = "test"
+		app.Config.OutputDir = tempDir
+		app.Config.InterfaceAddresses = "10.0.0.2/32"
+		app.Config.AllowedIPs = "0.0.0.0/0"
+		app.Config.DNS = "1.1.1.1"
+		app.Config.PersistentKeepalive = "25"
+		app.Config.Format = "ini,ipc"
+		app.Config.Archive = format.ArchiveZip
+		t.Setenv("SOURCE_DATE_EPOCH", "1700000000")
+
+		app.Provider = enums.NopProvider()
+		app.Ctx = context.Background()
+		app.ConfigGenerator = spyConfigGenerator
+		app.Formatters = newTestFormatterRegistry(t, app.Config)
+
+		err := run(app)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		entries, err := os.ReadDir(tempDir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Len(t, entries, 1)
+
+		archive, err := zip.OpenReader(filepath.Join(tempDir, "test.zip"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer archive.Close()
+
+		assert.Equal(
+			t,
+			[]string{"test_0.conf", "test_0.ipc", format.ManifestName},
+			lo.Map(archive.File, func(file *zip.File, _ int) string {
+				return file.Name
+			}),
+		)
+		for _, file := range archive.File {
+			assert.Equal(t, int64(1700000000), file.Modified.Unix())
+		}
+
+		info, err := entries[0].Info()
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, format.DefaultFileMode, info.Mode().Perm())
+	})
+
+	t.Run("index lists the configs and lands in the archive", func(t *testing.T) {
+		spyConfigGenerator := &SpyConfigGenerator{}
+		spyConfigGenerator.ListFunc = func(ctx context.Context, interfaceAddresses []netip.Prefix, allowedIPs []netip.Prefix, persistentKeepalive uint16, dns []netip.Addr) ([]wireguard2.Configuration, error) {
+			return []wireguard2.Configuration{
+				wireguard2.NewConfiguration(
+					"OEvHuuMpALNf7ZZkzUSGbT8vkj89aHrhLyqZlIn4rPU=",
+					interfaceAddresses,
+					dns,
+					[]wireguard2.PeerConfig{
+						wireguard2.NewPeerConfig(
+							"qIhtTW9K4iXWFo5Q4dOPdXg8/xubXr9yEGoN55D8xnA=",
+							netip.MustParseAddrPort("1.1.1.1:51820"),
+							allowedIPs,
+							persistentKeepalive,
+						),
+					},
+				),
+			}, nil
+		}
+		tempDir := t.TempDir()
+		app := &App{}
+		app.Config.Provider = "test"
+		app.Config.OutputDir = tempDir
+		app.Config.InterfaceAddresses = "10.0.0.2/32"
+		app.Config.AllowedIPs = "0.0.0.0/0"
+		app.Config.DNS = "1.1.1.1"
+		app.Config.PersistentKeepalive = "25"
+		app.Config.Format = "ini,ipc"
+		app.Config.Archive = format.ArchiveZip
+		app.Config.Index = true
+		app.Config.IndexQR = true
+
+		app.Provider = enums.NopProvider()
+		app.Ctx = context.Background()
+		app.ConfigGenerator = spyConfigGenerator
+		app.Formatters = newTestFormatterRegistry(t, app.Config)
+
+		err := run(app)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		entries, err := os.ReadDir(tempDir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Len(t, entries, 1)
+
+		archive, err := zip.OpenReader(filepath.Join(tempDir, "test.zip"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer archive.Close()
+
+		assert.Equal(
+			t,
+			[]string{
+				"test_0.conf",
+				"test_0.ipc",
+				format.CatalogHTMLName,
+				format.CatalogMarkdownName,
+				format.ManifestName,
+			},
+			lo.Map(archive.File, func(file *zip.File, _ int) string {
+				return file.Name
+			}),
+		)
+	})
+
+	t.Run("index flags without effect are rejected", func(t *testing.T) {
+		for _, tc := range []struct {
+			name   string
+			config Config
+			err    string
+		}{
+			{
+				name:   "qr without index",
+				config: Config{IndexQR: true},
+				err:    "--index-qr needs --index",
+			},
+			{
+				name:   "index on stdout",
+				config: Config{Index: true, Stdout: true},
+				err:    "--index with --stdout needs --archive",
+			},
+		} {
+			t.Run(tc.name, func(t *testing.T) {
+				app := &App{Config: tc.config}
+				app.Config.Provider = "test"
+				app.Config.Format = "ini"
+
+				err := run(app)
+
+				assert.EqualError(t, err, tc.err)
+			})
+		}
+	})
+
+	t.Run("unknown formats are rejected", func(t *testing.T) {
+		spyConfigGenerator := &SpyConfigGenerator{}
+		spyConfigGenerator.ListFunc = func(ctx context.Context, interfaceAddresses []netip.Prefix, allowedIPs []netip.Prefix, persistentKeepalive uint16, dns []netip.Addr) ([]wireguard2.Configuration, error) {
+			return nil, nil
+		}
+		app := &App{}
+		app.Config.Provider = "test"
+		app.Config.OutputDir = t.TempDir()
+		app.Config.InterfaceAddresses = "10.0.0.0/24"
+		app.Config.AllowedIPs = "0.0.0.0/0"
+		app.Config.DNS = "1.1.1.1"
+		app.Config.PersistentKeepalive = "25"
+		app.Config.Format = "ini,docx"
+
+		app.Provider = enums.NopProvider()
+		app.Ctx = context.Background()
+		app.ConfigGenerator = spyConfigGenerator
+		app.Formatters = newTestFormatterRegistry(t, app.Config)
+
+		err := run(app)
+
+		assert.ErrorContains(t, err, `unknown format "docx"`)
+	})
 }
+
+func newTestFormatterRegistry(t *testing.T, cfg Config) *format.Registry {
+	t.Helper()
+
+	registry, err := newFormatterRegistry(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return registry
+}
+
+// renamedFormatter registers a formatter a second time under another name.
+type renamedFormatter struct {
+	format.Formatter
+	name string
+}
+
+func (f renamedFormatter) Name() string { return f.name }
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/peterbourgon/ff/v4"
+
+	"github.com/xbnz/wireguard-config-generator/internal/enums"
+	wireguard2 "github.com/xbnz/wireguard-config-generator/pkg/wireguard"
+	nordvpn2 "github.com/xbnz/wireguard-config-generator/pkg/wireguard/providers/nordvpn"
+)
+
+const (
+	serversFormatCSV = "csv"
+	serversFormatTSV = "tsv"
+)
+
+// serversHeader names the columns of a server export.
+var serversHeader = []string{
+	"hostname",
+	"country",
+	"country_code",
+	"city",
+	"load",
+	"public_key",
+	"endpoint",
+	"endpoint_v6",
+}
+
+// ServersExportConfig holds the flags of servers export. The provider flags
+// mirror the ones of the root command.
+type ServersExportConfig struct {
+	Provider          string `ff:"long=provider, default=nordvpn, usage=Provider to list servers of"                                                         validate:"required,oneof=nordvpn"`
+	NordServerListUrl string `ff:"long=nord-server-list-url, default=https://api.nordvpn.com/v1/servers/recommendations, usage=URL to fetch server list from" validate:"omitempty,url"`
+	Format            string `ff:"long=format, default=csv, usage=Export format: csv or tsv"                                                                 validate:"required,oneof=csv tsv"`
+	Output            string `ff:"long=output, usage=File to write the export to instead of stdout"                                                          validate:"omitempty"`
+}
+
+func newServersCommand(stdout io.Writer) (*ff.Command, error) {
+	export, err := newServersExportCommand(stdout)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ff.Command{
+		Name:        "servers",
+		Usage:       "wireguard-config-generator servers <SUBCOMMAND>",
+		ShortHelp:   "inspect the server list of a provider",
+		Flags:       ff.NewFlagSet("servers"),
+		Subcommands: []*ff.Command{export},
+	}, nil
+}
+
+func newServersExportCommand(stdout io.Writer) (*ff.Command, error) {
+	var cfg ServersExportConfig
+	fs := ff.NewFlagSet("export")
+	if err := fs.AddStruct(&cfg); err != nil {
+		return nil, fmt.Errorf("add struct flags: %w", err)
+	}
+
+	return &ff.Command{
+		Name:      "export",
+		Usage:     "wireguard-config-generator servers export [FLAGS]",
+		ShortHelp: "dump the server list without generating configurations",
+		Flags:     fs,
+		Exec: func(ctx context.Context, _ []string) error {
+			validate := validator.New(validator.WithRequiredStructEnabled())
+			if err := validate.StructCtx(ctx, cfg); err != nil {
+				return err
+			}
+
+			serverer, err := newServerer(cfg, validate)
+			if err != nil {
+				return err
+			}
+
+			servers, err := serverer.List(ctx)
+			if err != nil {
+				return fmt.Errorf("list servers: %w", err)
+			}
+
+			if cfg.Output == "" {
+				return writeServers(stdout, cfg.Format, servers)
+			}
+
+			return writeServersFile(cfg.Output, cfg.Format, servers)
+		},
+	}, nil
+}
+
+// newServerer returns the server list of the configured provider. Listing
+// servers needs no credentials.
+func newServerer(
+	cfg ServersExportConfig,
+	validate *validator.Validate,
+) (wireguard2.Serverer, error) {
+	provider, err := enums.NewProvider(cfg.Provider)
+	if err != nil {
+		return nil, fmt.Errorf("create provider: %w", err)
+	}
+
+	switch provider {
+	case enums.NordVPNProvider():
+		return new(nordvpn2.NewServer(
+			newHTTPClient(),
+			cfg.NordServerListUrl,
+			validate,
+		)), nil
+	default:
+		return nil, fmt.Errorf("provider %s has no server list", provider)
+	}
+}
+
+func writeServersFile(
+	path string,
+	kind string,
+	servers []wireguard2.Server,
+) (err error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("create %s: %w", path, err)
+	}
+	defer func() {
+		if closeErr := file.Close(); closeErr != nil && err == nil {
+			err = fmt.Errorf("close %s: %w", path, closeErr)
+		}
+	}()
+
+	return writeServers(file, kind, servers)
+}
+
+// writeServers writes one row per server with every metadata field, sorted
+// by country, city and hostname so that exports of different days line up.
+// Unknown values, including a load of 0, are left empty.
+func writeServers(w io.Writer, kind string, servers []wireguard2.Server) error {
+	writer := csv.NewWriter(w)
+	switch kind {
+	case serversFormatCSV:
+	case serversFormatTSV:
+		writer.Comma = '\t'
+	default:
+		return fmt.Errorf("unknown export format %q", kind)
+	}
+
+	servers = slices.Clone(servers)
+	slices.SortStableFunc(servers, func(a, b wireguard2.Server) int {
+		return cmp.Or(
+			cmp.Compare(a.Metadata.Country, b.Metadata.Country),
+			cmp.Compare(a.Metadata.City, b.Metadata.City),
+			cmp.Compare(a.Metadata.Hostname, b.Metadata.Hostname),
+		)
+	})
+
+	if err := writer.Write(serversHeader); err != nil {
+		return fmt.Errorf("write servers: %w", err)
+	}
+
+	for _, server := range servers {
+		var load string
+		if server.Metadata.Load > 0 {
+			load = strconv.Itoa(server.Metadata.Load)
+		}
+
+		document := server.Document()
+		err := writer.Write([]string{
+			document.Metadata.Hostname,
+			document.Metadata.Country,
+			document.Metadata.CountryCode,
+			document.Metadata.City,
+			load,
+			document.PublicKey,
+			document.Endpoint,
+			document.EndpointV6,
+		})
+		if err != nil {
+			return fmt.Errorf("write servers: %w", err)
+		}
+	}
+
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return fmt.Errorf("write servers: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"bytes"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	wireguard2 "github.com/xbnz/wireguard-config-generator/pkg/wireguard"
+)
+
+func testServers() []wireguard2.Server {
+	return []wireguard2.Server{
+		{
+			PublicKey: "qIhtTW9K4iXWFo5Q4dOPdXg8/xubXr9yEGoN55D8xnA=",
+			Endpoint:  netip.MustParseAddrPort("62.3.36.228:51820"),
+			Metadata: wireguard2.ServerMetadata{
+				Hostname:    "de1047.nordvpn.com",
+				Country:     "Germany",
+				CountryCode: "DE",
+				City:        "Frankfurt",
+				Load:        17,
+			},
+		},
+		{
+			PublicKey: "3QnSLm4ZmSJhT8ZZzVJRThnx9uJfOWbSOHR7ocGDbgI=",
+			Endpoint:  netip.MustParseAddrPort("185.213.154.68:51820"),
+			EndpointV6: netip.MustParseAddrPort(
+				"[2a03:1b20:3:f011::a01f]:51820",
+			),
+			Metadata: wireguard2.ServerMetadata{
+				Hostname:    "se-got-wg-001",
+				Country:     "Sweden",
+				CountryCode: "SE",
+				City:        "Gothenburg, Västra Götaland",
+			},
+		},
+	}
+}
+
+func TestWriteServers(t *testing.T) {
+	t.Parallel()
+
+	// Sweden sorts after Germany, so the rows are flipped.
+	servers := testServers()
+	servers[0], servers[1] = servers[1], servers[0]
+
+	t.Run("csv quotes values with commas", func(t *testing.T) {
+		t.Parallel()
+
+		var out bytes.Buffer
+		if err := writeServers(&out, serversFormatCSV, servers); err != nil {
+			t.Fatal(err)
+		}
+
+		assert.Equal(t, strings.Join([]string{
+			"hostname,country,country_code,city,load,public_key,endpoint,endpoint_v6",
+			"de1047.nordvpn.com,Germany,DE,Frankfurt,17,qIhtTW9K4iXWFo5Q4dOPdXg8/xubXr9yEGoN55D8xnA=,62.3.36.228:51820,",
+			`se-got-wg-001,Sweden,SE,"Gothenburg, Västra Götaland",,3QnSLm4ZmSJhT8ZZzVJRThnx9uJfOWbSOHR7ocGDbgI=,185.213.154.68:51820,[2a03:1b20:3:f011::a01f]:51820`,
+			"",
+		}, "\n"), out.String())
+	})
+
+	t.Run("tsv separates values with tabs", func(t *testing.T) {
+		t.Parallel()
+
+		var out bytes.Buffer
+		if err := writeServers(&out, serversFormatTSV, servers); err != nil {
+			t.Fatal(err)
+		}
+
+		lines := strings.Split(out.String(), "\n")
+		assert.Equal(
+			t,
+			strings.Join(serversHeader, "\t"),
+			lines[0],
+		)
+		assert.Equal(
+			t,
+			"Gothenburg, Västra Götaland",
+			strings.Split(lines[2], "\t")[3],
+		)
+	})
+
+	t.Run("unknown formats are rejected", func(t *testing.T) {
+		t.Parallel()
+
+		err := writeServers(&bytes.Buffer{}, "xlsx", servers)
+		assert.ErrorContains(t, err, `unknown export format "xlsx"`)
+	})
+
+	t.Run("the export can go to a file", func(t *testing.T) {
+		t.Parallel()
+
+		path := filepath.Join(t.TempDir(), "servers.csv")
+		err := writeServersFile(path, serversFormatCSV, servers)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		content, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Len(t, strings.Split(strings.TrimSpace(string(content)), "\n"), 3)
+	})
+}
+
+func TestNewServerer(t *testing.T) {
+	t.Parallel()
+
+	_, err := newServerer(ServersExportConfig{Provider: "nop"}, nil)
+	assert.ErrorContains(t, err, "provider nop has no server list")
+}
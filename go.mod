@@ -7,8 +7,10 @@ require (
 	github.com/joho/godotenv v1.5.1
 	github.com/peterbourgon/ff/v4 v4.0.0-beta.1
 	github.com/samber/lo v1.52.0
+	github.com/skip2/go-qrcode v0.0.0-20200617195104-da1b6568686e
 	github.com/stretchr/testify v1.11.1
 	gopkg.in/dnaeon/go-vcr.v4 v4.0.6
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
@@ -22,5 +24,4 @@ require (
 	golang.org/x/crypto v0.48.0 // indirect
 	golang.org/x/sys v0.41.0 // indirect
 	golang.org/x/text v0.34.0 // indirect
-	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
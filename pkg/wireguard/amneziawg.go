@@ -0,0 +1,261 @@
+package wireguard
+
+import (
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"strconv"
+	"strings"
+)
+
+// Limits of the AmneziaWG parameters. Junk packets and padded handshake
+// messages have to fit into the 1280 bytes every IPv6 path carries, and the
+// padded handshake initiation (148 bytes) and response (92 bytes) must not
+// end up the same size, or they could be told apart by nothing but padding.
+const (
+	amneziaWGMaxJunkCount       = 128
+	amneziaWGMaxJunkSize        = 1280
+	amneziaWGMaxInitPadding     = amneziaWGMaxJunkSize - 148
+	amneziaWGMaxResponsePadding = amneziaWGMaxJunkSize - 92
+	amneziaWGPaddingSizeGap     = 148 - 92
+)
+
+// Ranges GenerateAmneziaWG picks from, as recommended by AmneziaWG.
+const (
+	amneziaWGMinJunkCount  = 4
+	amneziaWGMaxGenJunk    = 12
+	amneziaWGMinJunkMin    = 8
+	amneziaWGMaxJunkMin    = 40
+	amneziaWGMaxJunkMax    = 80
+	amneziaWGMinPadding    = 15
+	amneziaWGMaxGenPadding = 150
+	amneziaWGMinHeader     = 5
+)
+
+// AmneziaWG holds the obfuscation parameters of AmneziaWG, a WireGuard fork
+// that hides the handshake from deep packet inspection.
+//
+// Jc junk packets of random size between Jmin and Jmax bytes are sent ahead
+// of every handshake. They only change what the client sends, so a client
+// with nothing but these set still talks to a stock WireGuard server.
+//
+// S1 and S2 pad the handshake initiation and response, and H1 to H4 replace
+// the four message type headers. Both ends have to agree on them, so they
+// only work against an AmneziaWG server with the same values. A zero S pads
+// nothing and a zero H keeps WireGuard's message type, which is 1 to 4.
+type AmneziaWG struct {
+	Jc   uint16 `json:"jc,omitempty"   yaml:"jc,omitempty"`
+	Jmin uint16 `json:"jmin,omitempty" yaml:"jmin,omitempty"`
+	Jmax uint16 `json:"jmax,omitempty" yaml:"jmax,omitempty"`
+	S1   uint16 `json:"s1,omitempty"   yaml:"s1,omitempty"`
+	S2   uint16 `json:"s2,omitempty"   yaml:"s2,omitempty"`
+	H1   uint32 `json:"h1,omitempty"   yaml:"h1,omitempty"`
+	H2   uint32 `json:"h2,omitempty"   yaml:"h2,omitempty"`
+	H3   uint32 `json:"h3,omitempty"   yaml:"h3,omitempty"`
+	H4   uint32 `json:"h4,omitempty"   yaml:"h4,omitempty"`
+}
+
+// amneziaWGKeys are the wg-quick keys of the parameters in the order
+// AmneziaWG documents them.
+var amneziaWGKeys = []string{
+	"Jc", "Jmin", "Jmax", "S1", "S2", "H1", "H2", "H3", "H4",
+}
+
+// GenerateAmneziaWG returns a random set of junk packet parameters. With
+// headers, S1, S2 and H1 to H4 are randomised as well, which the server has
+// to be configured with too.
+func GenerateAmneziaWG(r *rand.Rand, headers bool) AmneziaWG {
+	between := func(low int, high int) uint16 {
+		return uint16(low + r.IntN(high-low+1))
+	}
+
+	params := AmneziaWG{
+		Jc:   between(amneziaWGMinJunkCount, amneziaWGMaxGenJunk),
+		Jmin: between(amneziaWGMinJunkMin, amneziaWGMaxJunkMin),
+	}
+	params.Jmax = between(int(params.Jmin)+1, amneziaWGMaxJunkMax)
+
+	if !headers {
+		return params
+	}
+
+	params.S1 = between(amneziaWGMinPadding, amneziaWGMaxGenPadding)
+	for {
+		params.S2 = between(amneziaWGMinPadding, amneziaWGMaxGenPadding)
+		if int(params.S1)+amneziaWGPaddingSizeGap != int(params.S2) {
+			break
+		}
+	}
+
+	seen := map[uint32]bool{}
+	fields := []*uint32{&params.H1, &params.H2, &params.H3, &params.H4}
+	for _, h := range fields {
+		for *h == 0 || seen[*h] {
+			*h = amneziaWGMinHeader +
+				r.Uint32N(math.MaxUint32-amneziaWGMinHeader+1)
+		}
+		seen[*h] = true
+	}
+
+	return params
+}
+
+// ParseAmneziaWG reads parameters from a comma separated list of key=value
+// pairs using the wg-quick key names, such as "Jc=4,Jmin=8,Jmax=80". Keys
+// are matched case-insensitively and missing ones are left at zero.
+func ParseAmneziaWG(value string) (AmneziaWG, error) {
+	var params AmneziaWG
+
+	for _, item := range splitINIList(value) {
+		key, value, ok := strings.Cut(item, "=")
+		if !ok {
+			return AmneziaWG{}, fmt.Errorf("expected key=value, got %q", item)
+		}
+
+		key = strings.ToLower(strings.TrimSpace(key))
+		known, err := params.parseKey(key, strings.TrimSpace(value))
+		if err != nil {
+			return AmneziaWG{}, err
+		}
+		if !known {
+			return AmneziaWG{}, fmt.Errorf("unknown parameter %q", key)
+		}
+	}
+
+	return params, nil
+}
+
+// parseKey sets the parameter named by the lower case key, reporting whether
+// the key is one.
+func (a *AmneziaWG) parseKey(key string, value string) (bool, error) {
+	var err error
+
+	switch key {
+	case "jc":
+		a.Jc, err = parseINIUint16(value)
+	case "jmin":
+		a.Jmin, err = parseINIUint16(value)
+	case "jmax":
+		a.Jmax, err = parseINIUint16(value)
+	case "s1":
+		a.S1, err = parseINIUint16(value)
+	case "s2":
+		a.S2, err = parseINIUint16(value)
+	case "h1":
+		a.H1, err = parseAmneziaWGHeader(value)
+	case "h2":
+		a.H2, err = parseAmneziaWGHeader(value)
+	case "h3":
+		a.H3, err = parseAmneziaWGHeader(value)
+	case "h4":
+		a.H4, err = parseAmneziaWGHeader(value)
+	default:
+		return false, nil
+	}
+
+	if err != nil {
+		return true, fmt.Errorf("invalid %s: %w", key, err)
+	}
+
+	return true, nil
+}
+
+// Lines returns the parameters as wg-quick keys, leaving out the zero ones,
+// which AmneziaWG reads as their defaults anyway.
+func (a *AmneziaWG) Lines() []INILine {
+	values := []uint32{
+		uint32(a.Jc),
+		uint32(a.Jmin),
+		uint32(a.Jmax),
+		uint32(a.S1),
+		uint32(a.S2),
+		a.H1,
+		a.H2,
+		a.H3,
+		a.H4,
+	}
+
+	var lines []INILine
+	for i, value := range values {
+		if value > 0 {
+			lines = append(lines, INILine{
+				Key:   amneziaWGKeys[i],
+				Value: strconv.FormatUint(uint64(value), 10),
+			})
+		}
+	}
+
+	return lines
+}
+
+// Problems lists every way the parameters would be rejected by AmneziaWG or
+// make the obfuscation pointless.
+func (a *AmneziaWG) Problems() []string {
+	var problems []string
+
+	add := func(format string, args ...any) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+
+	if a.Jc > amneziaWGMaxJunkCount {
+		add("Jc %d is above %d", a.Jc, amneziaWGMaxJunkCount)
+	}
+	if a.Jc > 0 && a.Jmax == 0 {
+		add("Jc %d sends junk packets but Jmax is 0", a.Jc)
+	}
+	if a.Jmin > a.Jmax {
+		add("Jmin %d is above Jmax %d", a.Jmin, a.Jmax)
+	}
+	if a.Jmax > amneziaWGMaxJunkSize {
+		add("Jmax %d is above %d", a.Jmax, amneziaWGMaxJunkSize)
+	}
+	if a.S1 > amneziaWGMaxInitPadding {
+		add("S1 %d is above %d", a.S1, amneziaWGMaxInitPadding)
+	}
+	if a.S2 > amneziaWGMaxResponsePadding {
+		add("S2 %d is above %d", a.S2, amneziaWGMaxResponsePadding)
+	}
+	if int(a.S1)+amneziaWGPaddingSizeGap == int(a.S2) {
+		add(
+			"S1 %d and S2 %d pad the handshake messages to the same size",
+			a.S1,
+			a.S2,
+		)
+	}
+
+	headers := a.headers()
+	for i := range headers {
+		for j := i + 1; j < len(headers); j++ {
+			if headers[i] == headers[j] {
+				add(
+					"H%d and H%d are both message type %d",
+					i+1,
+					j+1,
+					headers[i],
+				)
+			}
+		}
+	}
+
+	return problems
+}
+
+// headers returns the message types H1 to H4 stand for, filling in
+// WireGuard's for the zero ones.
+func (a *AmneziaWG) headers() []uint32 {
+	headers := []uint32{a.H1, a.H2, a.H3, a.H4}
+	for i, h := range headers {
+		if h == 0 {
+			headers[i] = uint32(i + 1)
+		}
+	}
+	return headers
+}
+
+func parseAmneziaWGHeader(value string) (uint32, error) {
+	parsed, err := strconv.ParseUint(value, 10, 32)
+	if err != nil {
+		return 0, err
+	}
+	return uint32(parsed), nil
+}
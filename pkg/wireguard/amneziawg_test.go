@@ -0,0 +1,143 @@
+package wireguard
+
+import (
+	"math/rand/v2"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGenerateAmneziaWG(t *testing.T) {
+	t.Parallel()
+
+	t.Run("junk parameters work with stock servers", func(t *testing.T) {
+		t.Parallel()
+
+		r := rand.New(rand.NewPCG(1, 2))
+		for range 1000 {
+			params := GenerateAmneziaWG(r, false)
+
+			assert.Empty(t, params.Problems())
+			assert.NotZero(t, params.Jc)
+			assert.Less(t, params.Jmin, params.Jmax)
+			assert.Zero(t, params.S1)
+			assert.Zero(t, params.S2)
+			assert.Equal(t, []uint32{1, 2, 3, 4}, params.headers())
+		}
+	})
+
+	t.Run("headers are random and valid", func(t *testing.T) {
+		t.Parallel()
+
+		r := rand.New(rand.NewPCG(3, 4))
+		for range 1000 {
+			params := GenerateAmneziaWG(r, true)
+
+			assert.Empty(t, params.Problems())
+			assert.NotZero(t, params.S1)
+			assert.NotZero(t, params.S2)
+			for _, h := range params.headers() {
+				assert.GreaterOrEqual(t, h, uint32(amneziaWGMinHeader))
+			}
+		}
+	})
+}
+
+func TestParseAmneziaWG(t *testing.T) {
+	t.Parallel()
+
+	t.Run("keys are case insensitive", func(t *testing.T) {
+		t.Parallel()
+
+		params, err := ParseAmneziaWG("Jc=4, jmin=8,JMAX=80,s1=20,h4=99")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		assert.Equal(
+			t,
+			AmneziaWG{Jc: 4, Jmin: 8, Jmax: 80, S1: 20, H4: 99},
+			params,
+		)
+	})
+
+	t.Run("unknown keys are rejected", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := ParseAmneziaWG("Jc=4,MTU=1280")
+		assert.ErrorContains(t, err, `unknown parameter "mtu"`)
+	})
+
+	t.Run("values are checked", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := ParseAmneziaWG("H1=-1")
+		assert.ErrorContains(t, err, "invalid h1")
+	})
+}
+
+func TestAmneziaWG_Problems(t *testing.T) {
+	t.Parallel()
+
+	params := AmneziaWG{
+		Jc:   200,
+		Jmin: 100,
+		Jmax: 50,
+		S1:   10,
+		S2:   66,
+		H1:   2,
+	}
+
+	assert.Equal(t, []string{
+		"Jc 200 is above 128",
+		"Jmin 100 is above Jmax 50",
+		"S1 10 and S2 66 pad the handshake messages to the same size",
+		"H1 and H2 are both message type 2",
+	}, params.Problems())
+}
+
+func TestAmneziaWG_INI(t *testing.T) {
+	t.Parallel()
+
+	content := strings.Join([]string{
+		"[Interface]",
+		"PrivateKey = OEvHuuMpALNf7ZZkzUSGbT8vkj89aHrhLyqZlIn4rPU=",
+		"Address = 10.5.0.2/32",
+		"DNS = 103.86.96.100",
+		"Jc = 4",
+		"Jmin = 8",
+		"Jmax = 80",
+		"S1 = 20",
+		"S2 = 40",
+		"H1 = 5",
+		"H2 = 6",
+		"H3 = 7",
+		"H4 = 8",
+		"",
+	}, "\n")
+
+	config, err := ParseINI(strings.NewReader(content))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, &AmneziaWG{
+		Jc:   4,
+		Jmin: 8,
+		Jmax: 80,
+		S1:   20,
+		S2:   40,
+		H1:   5,
+		H2:   6,
+		H3:   7,
+		H4:   8,
+	}, config.AmneziaWG)
+	assert.Empty(t, config.Extra)
+
+	ini, err := config.ToINIFormat()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, content, ini)
+}
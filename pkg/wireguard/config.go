@@ -12,7 +12,13 @@ type Configuration struct {
 	PrivateKey         string
 	InterfaceAddresses []netip.Prefix
 	DNS                []netip.Addr
+	DNSSearch          []string
+	ListenPort         uint16
+	FwMark             uint32
+	MTU                uint16
+	AmneziaWG          *AmneziaWG
 	Peers              []PeerConfig
+	Extra              []INILine
 }
 
 // NewConfiguration creates a new Configuration instance with the provided
@@ -31,11 +37,18 @@ func NewConfiguration(
 	}
 }
 
+// PeerConfig is a peer of a Configuration. EndpointHost holds an endpoint
+// naming a host rather than an address, such as vpn.example.com:51820, which
+// wg-quick resolves when bringing the interface up. It is only set when
+// Endpoint is not.
 type PeerConfig struct {
 	PublicKey           string
+	PresharedKey        string
 	Endpoint            netip.AddrPort
+	EndpointHost        string
 	AllowedIPs          []netip.Prefix
 	PersistentKeepalive uint16
+	Extra               []INILine
 }
 
 // NewPeerConfig creates a new PeerConfig with the specified public key,
@@ -54,6 +67,15 @@ func NewPeerConfig(
 	}
 }
 
+// EndpointString returns the endpoint the way wg-quick writes it, taken from
+// Endpoint or EndpointHost, or an empty string for a peer without one.
+func (p PeerConfig) EndpointString() string {
+	if p.Endpoint.IsValid() {
+		return p.Endpoint.String()
+	}
+	return p.EndpointHost
+}
+
 // ToIPCFormat serialises the configuration into the WireGuard UAPI key-value
 // format
 func (c *Configuration) ToIPCFormat() (string, error) {
@@ -64,7 +86,17 @@ func (c *Configuration) ToIPCFormat() (string, error) {
 		return "", fmt.Errorf("invalid private_key: %w", err)
 	}
 
-	fmt.Fprintf(&sb, "private_key=%s\nlisten_port=0\n", privHex)
+	fmt.Fprintf(&sb, "private_key=%s\nlisten_port=%d\n", privHex, c.ListenPort)
+
+	if c.FwMark > 0 {
+		fmt.Fprintf(&sb, "fwmark=%d\n", c.FwMark)
+	}
+
+	if c.AmneziaWG != nil {
+		for _, line := range c.AmneziaWG.Lines() {
+			fmt.Fprintf(&sb, "%s=%s\n", strings.ToLower(line.Key), line.Value)
+		}
+	}
 
 	for i, peer := range c.Peers {
 		pubHex, err := wgKeyToHex(peer.PublicKey)
@@ -74,6 +106,27 @@ func (c *Configuration) ToIPCFormat() (string, error) {
 
 		fmt.Fprintf(&sb, "public_key=%s\n", pubHex)
 
+		if peer.PresharedKey != "" {
+			pskHex, err := wgKeyToHex(peer.PresharedKey)
+			if err != nil {
+				return "", fmt.Errorf(
+					"invalid preshared_key for peer %d: %w",
+					i,
+					err,
+				)
+			}
+
+			fmt.Fprintf(&sb, "preshared_key=%s\n", pskHex)
+		}
+
+		if peer.EndpointHost != "" {
+			return "", fmt.Errorf(
+				"endpoint %s of peer %d is a host name, UAPI takes addresses only",
+				peer.EndpointHost,
+				i,
+			)
+		}
+
 		if peer.Endpoint.IsValid() {
 			fmt.Fprintf(&sb, "endpoint=%s\n", peer.Endpoint.String())
 		}
@@ -86,7 +139,7 @@ func (c *Configuration) ToIPCFormat() (string, error) {
 		if peer.PersistentKeepalive > 0 {
 			fmt.Fprintf(
 				&sb,
-				"persistent_keepalive_interval=%d\n\n",
+				"persistent_keepalive_interval=%d\n",
 				peer.PersistentKeepalive,
 			)
 		}
@@ -108,26 +161,55 @@ func (c *Configuration) ToINIFormat() (string, error) {
 		interfaceAddresses = append(interfaceAddresses, addr.String())
 	}
 
-	dnsAddresses := make([]string, 0, len(c.DNS))
+	dnsAddresses := make([]string, 0, len(c.DNS)+len(c.DNSSearch))
 	for _, addr := range c.DNS {
 		dnsAddresses = append(dnsAddresses, addr.String())
 	}
+	dnsAddresses = append(dnsAddresses, c.DNSSearch...)
 
 	fmt.Fprintf(&sb, "Address = %s\n", strings.Join(interfaceAddresses, ", "))
 	fmt.Fprintf(&sb, "DNS = %s\n", strings.Join(dnsAddresses, ", "))
 
+	if c.ListenPort > 0 {
+		fmt.Fprintf(&sb, "ListenPort = %d\n", c.ListenPort)
+	}
+
+	if c.FwMark > 0 {
+		fmt.Fprintf(&sb, "FwMark = %d\n", c.FwMark)
+	}
+
+	if c.MTU > 0 {
+		fmt.Fprintf(&sb, "MTU = %d\n", c.MTU)
+	}
+
+	if c.AmneziaWG != nil {
+		writeINILines(&sb, c.AmneziaWG.Lines())
+	}
+
+	writeINILines(&sb, c.Extra)
+
 	for _, peer := range c.Peers {
 		fmt.Fprintf(&sb, "\n[Peer]\n")
 		fmt.Fprintf(&sb, "PublicKey = %s\n", peer.PublicKey)
 
+		if peer.PresharedKey != "" {
+			fmt.Fprintf(&sb, "PresharedKey = %s\n", peer.PresharedKey)
+		}
+
 		allowedIPs := make([]string, 0, len(peer.AllowedIPs))
 		for _, addr := range peer.AllowedIPs {
 			allowedIPs = append(allowedIPs, addr.String())
 		}
 
 		fmt.Fprintf(&sb, "AllowedIPs = %s\n", strings.Join(allowedIPs, ", "))
-		fmt.Fprintf(&sb, "Endpoint = %s\n", peer.Endpoint.String())
+
+		if endpoint := peer.EndpointString(); endpoint != "" {
+			fmt.Fprintf(&sb, "Endpoint = %s\n", endpoint)
+		}
+
 		fmt.Fprintf(&sb, "PersistentKeepalive = %d\n", peer.PersistentKeepalive)
+
+		writeINILines(&sb, peer.Extra)
 	}
 
 	return sb.String(), nil
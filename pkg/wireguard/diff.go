@@ -0,0 +1,313 @@
+package wireguard
+
+import (
+	"fmt"
+	"net/netip"
+	"slices"
+	"strconv"
+)
+
+// ChangeKind describes how a field differs between two configurations.
+type ChangeKind string
+
+const (
+	ChangeAdded    ChangeKind = "added"
+	ChangeRemoved  ChangeKind = "removed"
+	ChangeModified ChangeKind = "changed"
+)
+
+// Change is a single semantic difference between two configurations. Peer is
+// the public key of the peer the change belongs to, or empty for interface
+// fields. Old and New are left empty for secrets.
+type Change struct {
+	Kind  ChangeKind `json:"kind"`
+	Field string     `json:"field"`
+	Peer  string     `json:"peer,omitempty"`
+	Old   string     `json:"old,omitempty"`
+	New   string     `json:"new,omitempty"`
+}
+
+func (c Change) String() string {
+	var prefix string
+	if c.Peer != "" {
+		prefix = fmt.Sprintf("peer %s: ", c.Peer)
+	}
+
+	switch {
+	case c.Kind == ChangeModified && c.Old == "" && c.New == "":
+		return fmt.Sprintf("%s%s changed", prefix, c.Field)
+	case c.Kind == ChangeModified:
+		return fmt.Sprintf(
+			"%s%s changed %s -> %s",
+			prefix,
+			c.Field,
+			c.Old,
+			c.New,
+		)
+	case c.Kind == ChangeAdded && c.New == "":
+		return fmt.Sprintf("%s%s added", prefix, c.Field)
+	case c.Kind == ChangeAdded:
+		return fmt.Sprintf("%s%s added %s", prefix, c.Field, c.New)
+	case c.Old == "":
+		return fmt.Sprintf("%s%s removed", prefix, c.Field)
+	default:
+		return fmt.Sprintf("%s%s removed %s", prefix, c.Field, c.Old)
+	}
+}
+
+// Diff compares two configurations semantically. The order of addresses, DNS
+// servers, AllowedIPs and peers does not matter, and comments are ignored.
+// Peers are matched by public key first and by endpoint second, so a server
+// that rotated its key shows up as a changed PublicKey rather than as a
+// removed and an added peer.
+func Diff(a Configuration, b Configuration) []Change {
+	var changes changeSet
+
+	changes.secret("PrivateKey", "", a.PrivateKey, b.PrivateKey)
+	changes.list(
+		"Address",
+		"",
+		stringsOf(a.InterfaceAddresses),
+		stringsOf(b.InterfaceAddresses),
+	)
+	changes.list("DNS", "", stringsOf(a.DNS), stringsOf(b.DNS))
+	changes.list("DNS", "", a.DNSSearch, b.DNSSearch)
+	changes.scalar(
+		"ListenPort",
+		"",
+		uintString(a.ListenPort),
+		uintString(b.ListenPort),
+	)
+	changes.scalar("FwMark", "", uintString(a.FwMark), uintString(b.FwMark))
+	changes.scalar("MTU", "", uintString(a.MTU), uintString(b.MTU))
+
+	before, after := amneziaWGValues(a.AmneziaWG), amneziaWGValues(b.AmneziaWG)
+	for _, key := range amneziaWGKeys {
+		changes.scalar(key, "", before[key], after[key])
+	}
+
+	changes.list("Extra", "", extraStrings(a.Extra), extraStrings(b.Extra))
+
+	pairs, removed, added := matchPeers(a.Peers, b.Peers)
+
+	for _, pair := range pairs {
+		changes.peer(pair[0], pair[1])
+	}
+
+	for _, peer := range removed {
+		changes = append(changes, Change{
+			Kind:  ChangeRemoved,
+			Field: "Peer",
+			Peer:  peer.PublicKey,
+		})
+	}
+
+	for _, peer := range added {
+		changes = append(changes, Change{
+			Kind:  ChangeAdded,
+			Field: "Peer",
+			Peer:  peer.PublicKey,
+		})
+	}
+
+	return changes
+}
+
+type changeSet []Change
+
+func (c *changeSet) peer(a PeerConfig, b PeerConfig) {
+	peer := b.PublicKey
+
+	c.scalar("PublicKey", peer, a.PublicKey, b.PublicKey)
+	c.secret("PresharedKey", peer, a.PresharedKey, b.PresharedKey)
+	c.scalar("Endpoint", peer, a.EndpointString(), b.EndpointString())
+	c.list(
+		"AllowedIPs",
+		peer,
+		stringsOf(a.AllowedIPs),
+		stringsOf(b.AllowedIPs),
+	)
+	c.scalar(
+		"PersistentKeepalive",
+		peer,
+		uintString(a.PersistentKeepalive),
+		uintString(b.PersistentKeepalive),
+	)
+	c.list("Extra", peer, extraStrings(a.Extra), extraStrings(b.Extra))
+}
+
+func (c *changeSet) scalar(
+	field string,
+	peer string,
+	before string,
+	after string,
+) {
+	switch {
+	case before == after:
+		return
+	case before == "":
+		*c = append(*c, Change{
+			Kind:  ChangeAdded,
+			Field: field,
+			Peer:  peer,
+			New:   after,
+		})
+	case after == "":
+		*c = append(*c, Change{
+			Kind:  ChangeRemoved,
+			Field: field,
+			Peer:  peer,
+			Old:   before,
+		})
+	default:
+		*c = append(*c, Change{
+			Kind:  ChangeModified,
+			Field: field,
+			Peer:  peer,
+			Old:   before,
+			New:   after,
+		})
+	}
+}
+
+func (c *changeSet) secret(
+	field string,
+	peer string,
+	before string,
+	after string,
+) {
+	switch {
+	case before == after:
+		return
+	case before == "":
+		*c = append(*c, Change{Kind: ChangeAdded, Field: field, Peer: peer})
+	case after == "":
+		*c = append(*c, Change{Kind: ChangeRemoved, Field: field, Peer: peer})
+	default:
+		*c = append(*c, Change{Kind: ChangeModified, Field: field, Peer: peer})
+	}
+}
+
+func (c *changeSet) list(
+	field string,
+	peer string,
+	before []string,
+	after []string,
+) {
+	for _, item := range before {
+		if !slices.Contains(after, item) {
+			*c = append(*c, Change{
+				Kind:  ChangeRemoved,
+				Field: field,
+				Peer:  peer,
+				Old:   item,
+			})
+		}
+	}
+
+	for _, item := range after {
+		if !slices.Contains(before, item) {
+			*c = append(*c, Change{
+				Kind:  ChangeAdded,
+				Field: field,
+				Peer:  peer,
+				New:   item,
+			})
+		}
+	}
+}
+
+// matchPeers pairs up the peers of two configurations by public key, then
+// pairs the remaining ones by endpoint. Peers without a partner are returned
+// as removed (only in a) or added (only in b).
+func matchPeers(
+	a []PeerConfig,
+	b []PeerConfig,
+) ([][2]PeerConfig, []PeerConfig, []PeerConfig) {
+	var pairs [][2]PeerConfig
+
+	matched := make([]bool, len(b))
+	var unmatched []PeerConfig
+
+	for _, peer := range a {
+		i := slices.IndexFunc(b, func(other PeerConfig) bool {
+			return other.PublicKey == peer.PublicKey
+		})
+		if i < 0 || matched[i] {
+			unmatched = append(unmatched, peer)
+			continue
+		}
+		matched[i] = true
+		pairs = append(pairs, [2]PeerConfig{peer, b[i]})
+	}
+
+	var removed []PeerConfig
+
+	for _, peer := range unmatched {
+		i := -1
+		for j, other := range b {
+			if !matched[j] && peer.Endpoint.IsValid() &&
+				other.Endpoint == peer.Endpoint {
+				i = j
+				break
+			}
+		}
+		if i < 0 {
+			removed = append(removed, peer)
+			continue
+		}
+		matched[i] = true
+		pairs = append(pairs, [2]PeerConfig{peer, b[i]})
+	}
+
+	var added []PeerConfig
+	for i, peer := range b {
+		if !matched[i] {
+			added = append(added, peer)
+		}
+	}
+
+	return pairs, removed, added
+}
+
+func stringsOf[T fmt.Stringer](values []T) []string {
+	result := make([]string, 0, len(values))
+	for _, value := range values {
+		result = append(result, value.String())
+	}
+	return result
+}
+
+func uintString[T uint16 | uint32](value T) string {
+	if value == 0 {
+		return ""
+	}
+	return strconv.FormatUint(uint64(value), 10)
+}
+
+func addrPortString(value netip.AddrPort) string {
+	if !value.IsValid() {
+		return ""
+	}
+	return value.String()
+}
+
+func extraStrings(lines []INILine) []string {
+	var result []string
+	for _, line := range lines {
+		if line.Key != "" {
+			result = append(result, line.Key+" = "+line.Value)
+		}
+	}
+	return result
+}
+
+func amneziaWGValues(params *AmneziaWG) map[string]string {
+	values := map[string]string{}
+	if params != nil {
+		for _, line := range params.Lines() {
+			values[line.Key] = line.Value
+		}
+	}
+	return values
+}
@@ -0,0 +1,175 @@
+package wireguard
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiff(t *testing.T) {
+	t.Parallel()
+
+	const (
+		privateKey = "OEvHuuMpALNf7ZZkzUSGbT8vkj89aHrhLyqZlIn4rPU="
+		publicKey  = "qIhtTW9K4iXWFo5Q4dOPdXg8/xubXr9yEGoN55D8xnA="
+		rotatedKey = "3QnSY6ObZk8KnDrHNyT4H3dBf7sNfFMx8Yl2YpXg1W0="
+	)
+
+	base := func() Configuration {
+		return NewConfiguration(
+			privateKey,
+			[]netip.Prefix{netip.MustParsePrefix("10.5.0.2/32")},
+			[]netip.Addr{netip.MustParseAddr("103.86.96.100")},
+			[]PeerConfig{
+				NewPeerConfig(
+					publicKey,
+					netip.MustParseAddrPort("62.3.36.228:51820"),
+					[]netip.Prefix{netip.MustParsePrefix("0.0.0.0/0")},
+					25,
+				),
+			},
+		)
+	}
+
+	tests := []struct {
+		name   string
+		mutate func(c *Configuration)
+		want   []Change
+	}{
+		{
+			name: "reordering and comments are not changes",
+			mutate: func(c *Configuration) {
+				c.DNS = []netip.Addr{netip.MustParseAddr("103.86.96.100")}
+				c.Extra = []INILine{{Comment: " regenerated"}}
+			},
+		},
+		{
+			name: "endpoint moved",
+			mutate: func(c *Configuration) {
+				c.Peers[0].Endpoint = netip.MustParseAddrPort(
+					"62.3.36.229:51820",
+				)
+			},
+			want: []Change{{
+				Kind:  ChangeModified,
+				Field: "Endpoint",
+				Peer:  publicKey,
+				Old:   "62.3.36.228:51820",
+				New:   "62.3.36.229:51820",
+			}},
+		},
+		{
+			name: "endpoint replaced by a host name",
+			mutate: func(c *Configuration) {
+				c.Peers[0].Endpoint = netip.AddrPort{}
+				c.Peers[0].EndpointHost = "vpn.example:51820"
+			},
+			want: []Change{{
+				Kind:  ChangeModified,
+				Field: "Endpoint",
+				Peer:  publicKey,
+				Old:   "62.3.36.228:51820",
+				New:   "vpn.example:51820",
+			}},
+		},
+		{
+			name: "server key rotated behind the same endpoint",
+			mutate: func(c *Configuration) {
+				c.Peers[0].PublicKey = rotatedKey
+			},
+			want: []Change{{
+				Kind:  ChangeModified,
+				Field: "PublicKey",
+				Peer:  rotatedKey,
+				Old:   publicKey,
+				New:   rotatedKey,
+			}},
+		},
+		{
+			name: "allowed IPs added and DNS replaced",
+			mutate: func(c *Configuration) {
+				c.Peers[0].AllowedIPs = append(
+					c.Peers[0].AllowedIPs,
+					netip.MustParsePrefix("::/0"),
+				)
+				c.DNS = []netip.Addr{netip.MustParseAddr("1.1.1.1")}
+			},
+			want: []Change{
+				{
+					Kind:  ChangeRemoved,
+					Field: "DNS",
+					Old:   "103.86.96.100",
+				},
+				{
+					Kind:  ChangeAdded,
+					Field: "DNS",
+					New:   "1.1.1.1",
+				},
+				{
+					Kind:  ChangeAdded,
+					Field: "AllowedIPs",
+					Peer:  publicKey,
+					New:   "::/0",
+				},
+			},
+		},
+		{
+			name: "private key values are not reported",
+			mutate: func(c *Configuration) {
+				c.PrivateKey = rotatedKey
+			},
+			want: []Change{{Kind: ChangeModified, Field: "PrivateKey"}},
+		},
+		{
+			name: "peer replaced",
+			mutate: func(c *Configuration) {
+				c.Peers[0].PublicKey = rotatedKey
+				c.Peers[0].Endpoint = netip.MustParseAddrPort(
+					"62.3.36.229:51820",
+				)
+			},
+			want: []Change{
+				{Kind: ChangeRemoved, Field: "Peer", Peer: publicKey},
+				{Kind: ChangeAdded, Field: "Peer", Peer: rotatedKey},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			after := base()
+			tt.mutate(&after)
+
+			assert.Equal(t, tt.want, Diff(base(), after))
+		})
+	}
+
+	t.Run("changes render as readable lines", func(t *testing.T) {
+		t.Parallel()
+
+		assert.Equal(
+			t,
+			"peer abc: Endpoint changed 1.1.1.1:1 -> 2.2.2.2:1",
+			Change{
+				Kind:  ChangeModified,
+				Field: "Endpoint",
+				Peer:  "abc",
+				Old:   "1.1.1.1:1",
+				New:   "2.2.2.2:1",
+			}.String(),
+		)
+		assert.Equal(
+			t,
+			"PrivateKey changed",
+			Change{Kind: ChangeModified, Field: "PrivateKey"}.String(),
+		)
+		assert.Equal(
+			t,
+			"DNS removed 1.1.1.1",
+			Change{Kind: ChangeRemoved, Field: "DNS", Old: "1.1.1.1"}.String(),
+		)
+	})
+}
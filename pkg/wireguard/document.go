@@ -0,0 +1,276 @@
+package wireguard
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/netip"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ConfigurationDocument is the machine-readable form of a Configuration used
+// by the JSON and YAML encodings. Its field names are stable, keys are kept
+// in base64 and addresses, prefixes and endpoints are written as strings.
+type ConfigurationDocument struct {
+	PrivateKey string         `json:"private_key"           yaml:"private_key"`
+	Addresses  []string       `json:"addresses"             yaml:"addresses"`
+	DNS        []string       `json:"dns,omitempty"         yaml:"dns,omitempty"`
+	DNSSearch  []string       `json:"dns_search,omitempty"  yaml:"dns_search,omitempty"`
+	ListenPort uint16         `json:"listen_port,omitempty" yaml:"listen_port,omitempty"`
+	FwMark     uint32         `json:"fwmark,omitempty"      yaml:"fwmark,omitempty"`
+	MTU        uint16         `json:"mtu,omitempty"         yaml:"mtu,omitempty"`
+	AmneziaWG  *AmneziaWG     `json:"amneziawg,omitempty"   yaml:"amneziawg,omitempty"`
+	Peers      []PeerDocument `json:"peers"                 yaml:"peers"`
+	Extra      []INILine      `json:"extra,omitempty"       yaml:"extra,omitempty"`
+}
+
+// PeerDocument is the machine-readable form of a PeerConfig.
+type PeerDocument struct {
+	PublicKey           string    `json:"public_key"                     yaml:"public_key"`
+	PresharedKey        string    `json:"preshared_key,omitempty"        yaml:"preshared_key,omitempty"`
+	Endpoint            string    `json:"endpoint,omitempty"             yaml:"endpoint,omitempty"`
+	AllowedIPs          []string  `json:"allowed_ips"                    yaml:"allowed_ips"`
+	PersistentKeepalive uint16    `json:"persistent_keepalive,omitempty" yaml:"persistent_keepalive,omitempty"`
+	Extra               []INILine `json:"extra,omitempty"                yaml:"extra,omitempty"`
+}
+
+// ServerDocument is the machine-readable form of a Server.
+type ServerDocument struct {
+	PublicKey  string         `json:"public_key,omitempty"  yaml:"public_key,omitempty"`
+	Endpoint   string         `json:"endpoint,omitempty"    yaml:"endpoint,omitempty"`
+	EndpointV6 string         `json:"endpoint_v6,omitempty" yaml:"endpoint_v6,omitempty"`
+	Metadata   ServerMetadata `json:"metadata"              yaml:"metadata"`
+}
+
+// Document returns the machine-readable form of the configuration.
+func (c *Configuration) Document() ConfigurationDocument {
+	document := ConfigurationDocument{
+		PrivateKey: c.PrivateKey,
+		Addresses:  stringsOf(c.InterfaceAddresses),
+		DNS:        stringsOf(c.DNS),
+		DNSSearch:  c.DNSSearch,
+		ListenPort: c.ListenPort,
+		FwMark:     c.FwMark,
+		MTU:        c.MTU,
+		AmneziaWG:  c.AmneziaWG,
+		Peers:      make([]PeerDocument, 0, len(c.Peers)),
+		Extra:      c.Extra,
+	}
+
+	for _, peer := range c.Peers {
+		document.Peers = append(document.Peers, PeerDocument{
+			PublicKey:           peer.PublicKey,
+			PresharedKey:        peer.PresharedKey,
+			Endpoint:            peer.EndpointString(),
+			AllowedIPs:          stringsOf(peer.AllowedIPs),
+			PersistentKeepalive: peer.PersistentKeepalive,
+			Extra:               peer.Extra,
+		})
+	}
+
+	return document
+}
+
+// Configuration parses the addresses, prefixes and endpoints of the document
+// back into a Configuration. Bare addresses are read as single-host
+// prefixes, like wg-quick does, and endpoints naming a host are read into
+// EndpointHost.
+func (d ConfigurationDocument) Configuration() (Configuration, error) {
+	config := Configuration{
+		PrivateKey: d.PrivateKey,
+		DNSSearch:  d.DNSSearch,
+		ListenPort: d.ListenPort,
+		FwMark:     d.FwMark,
+		MTU:        d.MTU,
+		AmneziaWG:  d.AmneziaWG,
+		Extra:      d.Extra,
+	}
+
+	var err error
+
+	config.InterfaceAddresses, err = documentPrefixes(d.Addresses)
+	if err != nil {
+		return Configuration{}, fmt.Errorf("invalid addresses: %w", err)
+	}
+
+	for _, value := range d.DNS {
+		addr, err := netip.ParseAddr(value)
+		if err != nil {
+			return Configuration{}, fmt.Errorf("invalid dns: %w", err)
+		}
+		config.DNS = append(config.DNS, addr)
+	}
+
+	for i, document := range d.Peers {
+		peer := PeerConfig{
+			PublicKey:           document.PublicKey,
+			PresharedKey:        document.PresharedKey,
+			PersistentKeepalive: document.PersistentKeepalive,
+			Extra:               document.Extra,
+		}
+
+		peer.AllowedIPs, err = documentPrefixes(document.AllowedIPs)
+		if err != nil {
+			return Configuration{}, fmt.Errorf(
+				"peer %d: invalid allowed_ips: %w",
+				i,
+				err,
+			)
+		}
+
+		if isHostEndpoint(document.Endpoint) {
+			peer.EndpointHost = document.Endpoint
+		} else {
+			peer.Endpoint, err = documentAddrPort(document.Endpoint)
+		}
+		if err != nil {
+			return Configuration{}, fmt.Errorf(
+				"peer %d: invalid endpoint: %w",
+				i,
+				err,
+			)
+		}
+
+		config.Peers = append(config.Peers, peer)
+	}
+
+	return config, nil
+}
+
+// Document returns the machine-readable form of the server.
+func (s Server) Document() ServerDocument {
+	return ServerDocument{
+		PublicKey:  s.PublicKey,
+		Endpoint:   addrPortString(s.Endpoint),
+		EndpointV6: addrPortString(s.EndpointV6),
+		Metadata:   s.Metadata,
+	}
+}
+
+// Server parses the endpoints of the document back into a Server.
+func (d ServerDocument) Server() (Server, error) {
+	endpoint, err := documentAddrPort(d.Endpoint)
+	if err != nil {
+		return Server{}, fmt.Errorf("invalid endpoint: %w", err)
+	}
+
+	endpointV6, err := documentAddrPort(d.EndpointV6)
+	if err != nil {
+		return Server{}, fmt.Errorf("invalid endpoint_v6: %w", err)
+	}
+
+	return Server{
+		PublicKey:  d.PublicKey,
+		Endpoint:   endpoint,
+		EndpointV6: endpointV6,
+		Metadata:   d.Metadata,
+	}, nil
+}
+
+// ToJSONFormat serialises the configuration into its JSON document
+func (c *Configuration) ToJSONFormat() (string, error) {
+	content, err := MarshalJSON(c.Document())
+	if err != nil {
+		return "", err
+	}
+	return string(content), nil
+}
+
+// ToYAMLFormat serialises the configuration into its YAML document
+func (c *Configuration) ToYAMLFormat() (string, error) {
+	content, err := MarshalYAML(c.Document())
+	if err != nil {
+		return "", err
+	}
+	return string(content), nil
+}
+
+// ParseJSON reads a configuration written by ToJSONFormat. Unknown fields are
+// rejected so that typos do not go unnoticed.
+func ParseJSON(r io.Reader) (Configuration, error) {
+	var document ConfigurationDocument
+	if err := UnmarshalJSON(r, &document); err != nil {
+		return Configuration{}, err
+	}
+	return document.Configuration()
+}
+
+// ParseYAML reads a configuration written by ToYAMLFormat. Unknown fields are
+// rejected so that typos do not go unnoticed.
+func ParseYAML(r io.Reader) (Configuration, error) {
+	var document ConfigurationDocument
+	if err := UnmarshalYAML(r, &document); err != nil {
+		return Configuration{}, err
+	}
+	return document.Configuration()
+}
+
+// MarshalJSON encodes a document as indented JSON followed by a newline.
+func MarshalJSON(document any) ([]byte, error) {
+	content, err := json.MarshalIndent(document, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("marshal json: %w", err)
+	}
+	return append(content, '\n'), nil
+}
+
+// MarshalYAML encodes a document as YAML indented by two spaces.
+func MarshalYAML(document any) ([]byte, error) {
+	var buf bytes.Buffer
+
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(document); err != nil {
+		return nil, fmt.Errorf("marshal yaml: %w", err)
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, fmt.Errorf("marshal yaml: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// UnmarshalJSON decodes a single JSON document into document, rejecting
+// unknown fields.
+func UnmarshalJSON(r io.Reader, document any) error {
+	decoder := json.NewDecoder(r)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(document); err != nil {
+		return fmt.Errorf("unmarshal json: %w", err)
+	}
+	return nil
+}
+
+// UnmarshalYAML decodes a single YAML document into document, rejecting
+// unknown fields.
+func UnmarshalYAML(r io.Reader, document any) error {
+	decoder := yaml.NewDecoder(r)
+	decoder.KnownFields(true)
+	if err := decoder.Decode(document); err != nil {
+		return fmt.Errorf("unmarshal yaml: %w", err)
+	}
+	return nil
+}
+
+func documentPrefixes(values []string) ([]netip.Prefix, error) {
+	var prefixes []netip.Prefix
+
+	for _, value := range values {
+		parsed, err := parseINIPrefixes(value)
+		if err != nil {
+			return nil, err
+		}
+		prefixes = append(prefixes, parsed...)
+	}
+
+	return prefixes, nil
+}
+
+func documentAddrPort(value string) (netip.AddrPort, error) {
+	if value == "" {
+		return netip.AddrPort{}, nil
+	}
+	return netip.ParseAddrPort(value)
+}
@@ -0,0 +1,128 @@
+package wireguard
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDocument(t *testing.T) {
+	t.Parallel()
+
+	file, err := os.Open(filepath.Join("testdata", "canonical.conf"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	config, err := ParseINI(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want, err := config.ToINIFormat()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		name   string
+		encode func() (string, error)
+		parse  func(content string) (Configuration, error)
+	}{
+		{
+			name:   "json",
+			encode: config.ToJSONFormat,
+			parse: func(content string) (Configuration, error) {
+				return ParseJSON(strings.NewReader(content))
+			},
+		},
+		{
+			name:   "yaml",
+			encode: config.ToYAMLFormat,
+			parse: func(content string) (Configuration, error) {
+				return ParseYAML(strings.NewReader(content))
+			},
+		},
+	} {
+		t.Run(tc.name+" round trips", func(t *testing.T) {
+			t.Parallel()
+
+			content, err := tc.encode()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			parsed, err := tc.parse(content)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := parsed.ToINIFormat()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			assert.Equal(t, want, got)
+		})
+	}
+
+	t.Run("bare addresses are single hosts", func(t *testing.T) {
+		t.Parallel()
+
+		parsed, err := ParseYAML(strings.NewReader(
+			"private_key: OEvHuuMpALNf7ZZkzUSGbT8vkj89aHrhLyqZlIn4rPU=\n" +
+				"addresses: [10.5.0.2, fd00::2]\n" +
+				"peers: []\n",
+		))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		assert.Equal(
+			t,
+			[]string{"10.5.0.2/32", "fd00::2/128"},
+			stringsOf(parsed.InterfaceAddresses),
+		)
+	})
+
+	t.Run("unknown fields are rejected", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := ParseJSON(strings.NewReader(`{"privatekey": "x"}`))
+		assert.ErrorContains(t, err, "unknown field")
+
+		_, err = ParseYAML(strings.NewReader("privatekey: x\n"))
+		assert.ErrorContains(t, err, "privatekey")
+	})
+
+	t.Run("host endpoints are kept", func(t *testing.T) {
+		t.Parallel()
+
+		parsed, err := ParseJSON(strings.NewReader(
+			`{"peers": [{"public_key": "x", "endpoint": "vpn.example:51820"}]}`,
+		))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		assert.Equal(t, "vpn.example:51820", parsed.Peers[0].EndpointHost)
+		assert.Equal(
+			t,
+			"vpn.example:51820",
+			parsed.Document().Peers[0].Endpoint,
+		)
+	})
+
+	t.Run("malformed endpoints name their peer", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := ParseJSON(strings.NewReader(
+			`{"peers": [{"public_key": "x", "endpoint": "nope"}]}`,
+		))
+		assert.ErrorContains(t, err, "peer 0: invalid endpoint")
+	})
+}
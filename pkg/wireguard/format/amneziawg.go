@@ -0,0 +1,95 @@
+package format
+
+import (
+	"fmt"
+	"math/rand/v2"
+
+	"github.com/xbnz/wireguard-config-generator/pkg/wireguard"
+)
+
+// AmneziaWGOptions tunes the AmneziaWG output.
+type AmneziaWGOptions struct {
+	// Parameters are written into every configuration, such as the ones an
+	// AmneziaWG server was set up with. When nil, a configuration's own
+	// parameters are kept and the others get a generated set.
+	Parameters *wireguard.AmneziaWG
+	// Headers generates S1, S2 and H1 to H4 as well as the junk packet
+	// parameters. The result no longer talks to stock WireGuard servers.
+	Headers bool
+	// Rand is the source parameters are generated from.
+	Rand *rand.Rand
+}
+
+// AmneziaWG renders configurations in the wg-quick INI format extended with
+// the obfuscation parameters of AmneziaWG, as read by awg-quick and the
+// AmneziaWG and AmneziaVPN clients.
+type AmneziaWG struct {
+	options AmneziaWGOptions
+}
+
+// NewAmneziaWG initializes and returns an AmneziaWG formatter.
+func NewAmneziaWG(options AmneziaWGOptions) *AmneziaWG {
+	return &AmneziaWG{options: options}
+}
+
+func (f *AmneziaWG) Name() string { return "amneziawg" }
+
+// Extension keeps the files apart from the ini format, which plain
+// wg-quick would choke on.
+func (f *AmneziaWG) Extension() string { return ".awg.conf" }
+
+// Format renders a single configuration with a freshly generated set of
+// parameters unless it has its own or Parameters are set.
+func (f *AmneziaWG) Format(item Item) ([]byte, error) {
+	return f.render(item, f.generate())
+}
+
+// FormatBatch writes one file per configuration. Generated parameters are
+// shared by all of them, so a single AmneziaWG server set up with them
+// accepts every configuration of the run.
+func (f *AmneziaWG) FormatBatch(items []Item) ([]File, error) {
+	generated := f.generate()
+	files := make([]File, 0, len(items))
+
+	for _, item := range items {
+		content, err := f.render(item, generated)
+		if err != nil {
+			return nil, fmt.Errorf(
+				"format %s as %s: %w",
+				item.Name,
+				f.Name(),
+				err,
+			)
+		}
+
+		files = append(files, File{
+			Name:    item.Name + f.Extension(),
+			Content: content,
+		})
+	}
+
+	return files, nil
+}
+
+func (f *AmneziaWG) generate() wireguard.AmneziaWG {
+	if f.options.Parameters != nil {
+		return *f.options.Parameters
+	}
+	return wireguard.GenerateAmneziaWG(f.options.Rand, f.options.Headers)
+}
+
+func (f *AmneziaWG) render(
+	item Item,
+	generated wireguard.AmneziaWG,
+) ([]byte, error) {
+	config := item.Configuration
+	if f.options.Parameters != nil || config.AmneziaWG == nil {
+		config.AmneziaWG = &generated
+	}
+
+	ini, err := config.ToINIFormat()
+	if err != nil {
+		return nil, err
+	}
+	return []byte(ini), nil
+}
@@ -0,0 +1,78 @@
+package format
+
+import (
+	"bytes"
+	"math/rand/v2"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/xbnz/wireguard-config-generator/pkg/wireguard"
+)
+
+func TestAmneziaWG(t *testing.T) {
+	t.Parallel()
+
+	t.Run("generated parameters are shared by the batch", func(t *testing.T) {
+		t.Parallel()
+
+		f := NewAmneziaWG(AmneziaWGOptions{
+			Headers: true,
+			Rand:    rand.New(rand.NewPCG(1, 2)),
+		})
+
+		files, err := f.FormatBatch(testItems())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		first := amneziaWGParameters(t, files[0].Content)
+		second := amneziaWGParameters(t, files[1].Content)
+		assert.Equal(t, first, second)
+		assert.NotZero(t, first.H1)
+	})
+
+	t.Run("configured parameters win", func(t *testing.T) {
+		t.Parallel()
+
+		parameters := wireguard.AmneziaWG{Jc: 3, Jmin: 10, Jmax: 50}
+		item := testItems()[0]
+		item.Configuration.AmneziaWG = &wireguard.AmneziaWG{Jc: 9}
+
+		content, err := NewAmneziaWG(AmneziaWGOptions{
+			Parameters: &parameters,
+		}).Format(item)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		assert.Equal(t, &parameters, amneziaWGParameters(t, content))
+	})
+
+	t.Run("parameters of a configuration are kept", func(t *testing.T) {
+		t.Parallel()
+
+		parameters := wireguard.AmneziaWG{Jc: 9, Jmin: 20, Jmax: 30}
+		item := testItems()[0]
+		item.Configuration.AmneziaWG = &parameters
+
+		content, err := NewAmneziaWG(AmneziaWGOptions{
+			Rand: rand.New(rand.NewPCG(1, 2)),
+		}).Format(item)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		assert.Equal(t, &parameters, amneziaWGParameters(t, content))
+	})
+}
+
+func amneziaWGParameters(t *testing.T, content []byte) *wireguard.AmneziaWG {
+	t.Helper()
+
+	config, err := wireguard.ParseINI(bytes.NewReader(content))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return config.AmneziaWG
+}
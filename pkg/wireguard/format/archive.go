@@ -0,0 +1,206 @@
+package format
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"slices"
+	"time"
+
+	"github.com/xbnz/wireguard-config-generator/pkg/wireguard"
+)
+
+const (
+	ArchiveZip   = "zip"
+	ArchiveTarGz = "tar.gz"
+
+	// ManifestName is the name of the manifest inside an archive.
+	ManifestName = "manifest.json"
+
+	// ManifestVersion is bumped whenever the manifest changes in a way
+	// consumers have to handle.
+	ManifestVersion = 1
+)
+
+// Output is a rendered file together with the name of the format that
+// rendered it.
+type Output struct {
+	Format string
+	File
+}
+
+// Manifest describes the contents of an archive: the configurations of the
+// run with the servers they point at, and every file with its hash.
+type Manifest struct {
+	Version        int                     `json:"version"`
+	Configurations []ManifestConfiguration `json:"configurations"`
+	Files          []ManifestFile          `json:"files"`
+}
+
+// ManifestConfiguration is a configuration of the run. Identity is the
+// public key of the server, or its hostname without a peer, so it stays the
+// same when the configuration is regenerated from a reordered server list.
+type ManifestConfiguration struct {
+	Name     string                   `json:"name"`
+	Identity string                   `json:"identity"`
+	Server   wireguard.ServerDocument `json:"server"`
+}
+
+// ManifestFile is a file of the archive. Configuration names the
+// configuration the file belongs to and is empty for files shared by the
+// whole run, such as a Clash profile.
+type ManifestFile struct {
+	Name          string `json:"name"`
+	Format        string `json:"format"`
+	Configuration string `json:"configuration,omitempty"`
+	SHA256        string `json:"sha256"`
+	Size          int    `json:"size"`
+}
+
+// NewManifest describes the outputs rendered for items. A file belongs to a
+// configuration when it is named after it, like Each names them.
+func NewManifest(items []Item, outputs []Output) Manifest {
+	manifest := Manifest{
+		Version:        ManifestVersion,
+		Configurations: make([]ManifestConfiguration, 0, len(items)),
+		Files:          make([]ManifestFile, 0, len(outputs)),
+	}
+
+	for _, item := range items {
+		manifest.Configurations = append(
+			manifest.Configurations,
+			ManifestConfiguration{
+				Name:     item.Name,
+				Identity: identity(item),
+				Server:   item.Server.Document(),
+			},
+		)
+	}
+
+	for _, output := range outputs {
+		sum := sha256.Sum256(output.Content)
+		file := ManifestFile{
+			Name:   output.Name,
+			Format: output.Format,
+			SHA256: hex.EncodeToString(sum[:]),
+			Size:   len(output.Content),
+		}
+
+		if i := itemIndex(items, output.Name); i >= 0 {
+			file.Configuration = items[i].Name
+		}
+
+		manifest.Files = append(manifest.Files, file)
+	}
+
+	return manifest
+}
+
+// WriteArchive writes the outputs together with their manifest as a zip or
+// gzipped tar archive of the given kind. Every entry gets modTime, so the
+// same outputs and modTime always make the same archive.
+func WriteArchive(
+	w io.Writer,
+	kind string,
+	items []Item,
+	outputs []Output,
+	modTime time.Time,
+) error {
+	if slices.ContainsFunc(outputs, func(output Output) bool {
+		return output.Name == ManifestName
+	}) {
+		return fmt.Errorf("%s is reserved for the manifest", ManifestName)
+	}
+
+	manifest, err := wireguard.MarshalJSON(NewManifest(items, outputs))
+	if err != nil {
+		return fmt.Errorf("marshal manifest: %w", err)
+	}
+
+	files := make([]File, 0, len(outputs)+1)
+	for _, output := range outputs {
+		files = append(files, output.File)
+	}
+	files = append(files, File{
+		Name:    ManifestName,
+		Content: manifest,
+		Mode:    publicFileMode,
+	})
+
+	switch kind {
+	case ArchiveZip:
+		return writeZip(w, files, modTime)
+	case ArchiveTarGz:
+		return writeTarGz(w, files, modTime)
+	default:
+		return fmt.Errorf("unknown archive kind %q", kind)
+	}
+}
+
+func writeZip(w io.Writer, files []File, modTime time.Time) error {
+	archive := zip.NewWriter(w)
+
+	for _, file := range files {
+		header := &zip.FileHeader{
+			Name:     file.Name,
+			Method:   zip.Deflate,
+			Modified: modTime,
+		}
+		header.SetMode(archiveMode(file))
+
+		entry, err := archive.CreateHeader(header)
+		if err != nil {
+			return fmt.Errorf("add %s to archive: %w", file.Name, err)
+		}
+		if _, err := entry.Write(file.Content); err != nil {
+			return fmt.Errorf("add %s to archive: %w", file.Name, err)
+		}
+	}
+
+	if err := archive.Close(); err != nil {
+		return fmt.Errorf("close archive: %w", err)
+	}
+	return nil
+}
+
+func writeTarGz(w io.Writer, files []File, modTime time.Time) error {
+	compressed := gzip.NewWriter(w)
+	archive := tar.NewWriter(compressed)
+
+	for _, file := range files {
+		err := archive.WriteHeader(&tar.Header{
+			Typeflag: tar.TypeReg,
+			Name:     file.Name,
+			Mode:     int64(archiveMode(file)),
+			Size:     int64(len(file.Content)),
+			ModTime:  modTime,
+			Format:   tar.FormatPAX,
+		})
+		if err != nil {
+			return fmt.Errorf("add %s to archive: %w", file.Name, err)
+		}
+		if _, err := archive.Write(file.Content); err != nil {
+			return fmt.Errorf("add %s to archive: %w", file.Name, err)
+		}
+	}
+
+	if err := archive.Close(); err != nil {
+		return fmt.Errorf("close archive: %w", err)
+	}
+	if err := compressed.Close(); err != nil {
+		return fmt.Errorf("close archive: %w", err)
+	}
+	return nil
+}
+
+func archiveMode(file File) fs.FileMode {
+	if file.Mode == 0 {
+		return DefaultFileMode
+	}
+	return file.Mode
+}
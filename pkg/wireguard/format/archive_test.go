@@ -0,0 +1,179 @@
+package format
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"io/fs"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewManifest(t *testing.T) {
+	t.Parallel()
+
+	items := testItems()
+	manifest := NewManifest(items, []Output{
+		{Format: "ini", File: File{Name: "nordvpn_0.conf"}},
+		{Format: "ini", File: File{Name: "mullvad_1.conf"}},
+		{Format: "clash", File: File{Name: "clash.yaml"}},
+	})
+
+	assert.Equal(t, ManifestVersion, manifest.Version)
+	assert.Equal(
+		t,
+		"3QnSY6ObZk8KnDrHNyT4H3dBf7sNfFMx8Yl2YpXg1W0=",
+		manifest.Configurations[1].Identity,
+	)
+	assert.Equal(
+		t,
+		"se-got-wg-001",
+		manifest.Configurations[1].Server.Metadata.Hostname,
+	)
+
+	assert.Equal(t, "nordvpn_0", manifest.Files[0].Configuration)
+	assert.Equal(t, "mullvad_1", manifest.Files[1].Configuration)
+	assert.Empty(t, manifest.Files[2].Configuration)
+	assert.Equal(
+		t,
+		"e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855",
+		manifest.Files[2].SHA256,
+	)
+}
+
+func TestWriteArchive(t *testing.T) {
+	t.Parallel()
+
+	outputs := []Output{
+		{Format: "ini", File: File{
+			Name:    "nordvpn_0.conf",
+			Content: []byte("[Interface]\n"),
+		}},
+		{Format: "openbsd", File: File{
+			Name:    "hostname.wg0",
+			Content: []byte("up\n"),
+			Mode:    0o640,
+		}},
+	}
+	modTime := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	t.Run("zip", func(t *testing.T) {
+		t.Parallel()
+
+		var buf bytes.Buffer
+		err := WriteArchive(&buf, ArchiveZip, testItems(), outputs, modTime)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		archive, err := zip.NewReader(
+			bytes.NewReader(buf.Bytes()),
+			int64(buf.Len()),
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		contents := map[string]string{}
+		for _, file := range archive.File {
+			reader, err := file.Open()
+			if err != nil {
+				t.Fatal(err)
+			}
+			content, err := io.ReadAll(reader)
+			if err != nil {
+				t.Fatal(err)
+			}
+			contents[file.Name] = string(content)
+		}
+
+		assert.Equal(t, "[Interface]\n", contents["nordvpn_0.conf"])
+		assert.Equal(t, fs.FileMode(0o600), archive.File[0].Mode().Perm())
+		assert.Equal(t, fs.FileMode(0o640), archive.File[1].Mode().Perm())
+		assertManifest(t, contents[ManifestName])
+	})
+
+	t.Run("tar.gz", func(t *testing.T) {
+		t.Parallel()
+
+		var buf bytes.Buffer
+		err := WriteArchive(&buf, ArchiveTarGz, testItems(), outputs, modTime)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		compressed, err := gzip.NewReader(&buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		archive := tar.NewReader(compressed)
+
+		var names []string
+		contents := map[string]string{}
+		for {
+			header, err := archive.Next()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			content, err := io.ReadAll(archive)
+			if err != nil {
+				t.Fatal(err)
+			}
+			names = append(names, header.Name)
+			contents[header.Name] = string(content)
+
+			assert.True(t, header.ModTime.Equal(modTime))
+			switch header.Name {
+			case "nordvpn_0.conf":
+				assert.Equal(t, int64(0o600), header.Mode)
+			case "hostname.wg0":
+				assert.Equal(t, int64(0o640), header.Mode)
+			}
+		}
+
+		assert.Equal(
+			t,
+			[]string{"nordvpn_0.conf", "hostname.wg0", ManifestName},
+			names,
+		)
+		assertManifest(t, contents[ManifestName])
+	})
+
+	t.Run("manifest name is reserved", func(t *testing.T) {
+		t.Parallel()
+
+		err := WriteArchive(
+			io.Discard,
+			ArchiveZip,
+			testItems(),
+			[]Output{{Format: "json", File: File{Name: ManifestName}}},
+			modTime,
+		)
+		assert.ErrorContains(t, err, "manifest.json is reserved")
+	})
+}
+
+func assertManifest(t *testing.T, content string) {
+	t.Helper()
+
+	var manifest Manifest
+	if err := json.Unmarshal([]byte(content), &manifest); err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Len(t, manifest.Configurations, 2)
+	assert.Equal(t, []string{"nordvpn_0", ""}, []string{
+		manifest.Files[0].Configuration,
+		manifest.Files[1].Configuration,
+	})
+	assert.Equal(t, "openbsd", manifest.Files[1].Format)
+}
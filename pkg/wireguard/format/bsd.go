@@ -0,0 +1,189 @@
+package format
+
+import (
+	"fmt"
+	"net/netip"
+	"strings"
+
+	"github.com/xbnz/wireguard-config-generator/pkg/wireguard"
+)
+
+// bsdRoutes derives the routes of a tunnel from its peers' AllowedIPs for
+// the address families it has an interface address in. Like wg-quick on the
+// BSDs, default routes are split into two halves that win over the existing
+// default route without replacing it. The halves are returned on their own,
+// together with the endpoints of the peers carrying them, which have to be
+// kept on the existing gateway before the halves are added.
+func bsdRoutes(
+	config wireguard.Configuration,
+) (routes []netip.Prefix, halves []netip.Prefix, bypass []netip.Addr) {
+	addressesV4, addressesV6 := splitPrefixes(config.InterfaceAddresses)
+
+	for _, peer := range config.Peers {
+		for _, prefix := range peer.AllowedIPs {
+			prefix = prefix.Masked()
+			if prefix.Addr().Is4() && len(addressesV4) == 0 ||
+				prefix.Addr().Is6() && len(addressesV6) == 0 {
+				continue
+			}
+
+			if prefix.Bits() > 0 {
+				routes = append(routes, prefix)
+				continue
+			}
+
+			halves = append(halves, bsdDefaultRouteHalves(prefix)...)
+			if peer.Endpoint.IsValid() &&
+				peer.Endpoint.Addr().Is4() == prefix.Addr().Is4() {
+				bypass = append(bypass, peer.Endpoint.Addr())
+			}
+		}
+	}
+
+	return routes, halves, bypass
+}
+
+func bsdDefaultRouteHalves(prefix netip.Prefix) []netip.Prefix {
+	if prefix.Addr().Is4() {
+		return []netip.Prefix{
+			netip.MustParsePrefix("0.0.0.0/1"),
+			netip.MustParsePrefix("128.0.0.0/1"),
+		}
+	}
+	return []netip.Prefix{
+		netip.MustParsePrefix("::/1"),
+		netip.MustParsePrefix("8000::/1"),
+	}
+}
+
+// bsdFamily returns the route(8) address family flag of addr.
+func bsdFamily(addr netip.Addr) string {
+	if addr.Is4() {
+		return "-inet"
+	}
+	return "-inet6"
+}
+
+// bsdFamilyName names the address family of addr in messages.
+func bsdFamilyName(addr netip.Addr) string {
+	if addr.Is4() {
+		return "IPv4"
+	}
+	return "IPv6"
+}
+
+// bsdEndpointScriptName names the script of bsdEndpointScript for the
+// interface name of the formatter called platform.
+func bsdEndpointScriptName(platform string, name string) string {
+	return fmt.Sprintf("%s.%s.up", platform, name)
+}
+
+// bsdEndpointScript renders a script adding host routes for the endpoints in
+// bypass through the default gateway of their family, followed by the routes
+// through the interface called name. The gateway is looked up when the
+// script runs, as only the system bringing up the interface knows it. The
+// routes are deleted first, which keeps a second run from looking up the
+// tunnel as the gateway, and the script stops at the first route it cannot
+// add.
+func bsdEndpointScript(
+	name string,
+	bypass []netip.Addr,
+	routes []netip.Prefix,
+) []byte {
+	var sb strings.Builder
+
+	fmt.Fprintf(&sb, "#!/bin/sh\n")
+	fmt.Fprintf(
+		&sb,
+		"# Keeps the endpoints of %s on the current default gateway.\n",
+		name,
+	)
+	if len(routes) > 0 {
+		fmt.Fprintf(&sb, "# Routes through %s are added after them.\n", name)
+	}
+	fmt.Fprintf(&sb, "set -e\n")
+
+	for _, route := range routes {
+		fmt.Fprintf(
+			&sb,
+			"route -q delete %s -net %s || true\n",
+			bsdFamily(route.Addr()),
+			route,
+		)
+	}
+	for _, endpoint := range bypass {
+		fmt.Fprintf(
+			&sb,
+			"route -q delete %s %s || true\n",
+			bsdFamily(endpoint),
+			endpoint,
+		)
+	}
+
+	for _, endpoint := range bypass {
+		fmt.Fprintf(
+			&sb,
+			"gateway=$(route -n get %s default | "+
+				"awk '/gateway:/ { print $2 }')\n",
+			bsdFamily(endpoint),
+		)
+		fmt.Fprintf(
+			&sb,
+			"route -q add %s %s \"${gateway:?no %s default route}\"\n",
+			bsdFamily(endpoint),
+			endpoint,
+			bsdFamilyName(endpoint),
+		)
+	}
+	for _, route := range routes {
+		fmt.Fprintf(
+			&sb,
+			"route -q add %s -net %s -interface %s\n",
+			bsdFamily(route.Addr()),
+			route,
+			name,
+		)
+	}
+
+	return []byte(sb.String())
+}
+
+// wgSetconf renders the parts of a configuration wg(8) setconf understands,
+// leaving addresses, DNS and MTU to the system's own network configuration.
+func wgSetconf(config wireguard.Configuration) string {
+	var sb strings.Builder
+
+	fmt.Fprintf(&sb, "[Interface]\n")
+	fmt.Fprintf(&sb, "PrivateKey = %s\n", config.PrivateKey)
+	if config.ListenPort > 0 {
+		fmt.Fprintf(&sb, "ListenPort = %d\n", config.ListenPort)
+	}
+	if config.FwMark > 0 {
+		fmt.Fprintf(&sb, "FwMark = %d\n", config.FwMark)
+	}
+
+	for _, peer := range config.Peers {
+		fmt.Fprintf(&sb, "\n[Peer]\n")
+		fmt.Fprintf(&sb, "PublicKey = %s\n", peer.PublicKey)
+		if peer.PresharedKey != "" {
+			fmt.Fprintf(&sb, "PresharedKey = %s\n", peer.PresharedKey)
+		}
+		fmt.Fprintf(
+			&sb,
+			"AllowedIPs = %s\n",
+			joinStrings(peer.AllowedIPs, ", "),
+		)
+		if peer.Endpoint.IsValid() {
+			fmt.Fprintf(&sb, "Endpoint = %s\n", peer.Endpoint)
+		}
+		if peer.PersistentKeepalive > 0 {
+			fmt.Fprintf(
+				&sb,
+				"PersistentKeepalive = %d\n",
+				peer.PersistentKeepalive,
+			)
+		}
+	}
+
+	return sb.String()
+}
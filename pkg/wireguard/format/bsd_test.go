@@ -0,0 +1,141 @@
+package format
+
+import (
+	"io/fs"
+	"net/netip"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBSDRoutes(t *testing.T) {
+	t.Parallel()
+
+	t.Run("default routes are split and skip the endpoint", func(t *testing.T) {
+		t.Parallel()
+
+		routes, halves, bypass := bsdRoutes(testItems()[0].Configuration)
+
+		assert.Empty(t, routes)
+		assert.Equal(t, []string{"0.0.0.0/1", "128.0.0.0/1"}, toStrings(halves))
+		assert.Equal(t, []string{"62.3.36.228"}, toStrings(bypass))
+	})
+
+	t.Run("families without an address are not routed", func(t *testing.T) {
+		t.Parallel()
+
+		config := testItems()[1].Configuration
+		config.InterfaceAddresses = config.InterfaceAddresses[:1]
+		config.Peers[0].AllowedIPs = []netip.Prefix{
+			netip.MustParsePrefix("10.0.0.1/8"),
+			netip.MustParsePrefix("::/0"),
+		}
+
+		routes, halves, bypass := bsdRoutes(config)
+
+		assert.Equal(t, []string{"10.0.0.0/8"}, toStrings(routes))
+		assert.Empty(t, halves)
+		assert.Empty(t, bypass)
+	})
+}
+
+func TestFreeBSD(t *testing.T) {
+	t.Parallel()
+
+	item := testItems()[1]
+	item.Configuration.InterfaceAddresses = append(
+		item.Configuration.InterfaceAddresses,
+		netip.MustParsePrefix("10.64.0.3/32"),
+		netip.MustParsePrefix("fc00:bbbb:bbbb:bb01::3/128"),
+	)
+
+	files, err := NewFreeBSD().FormatBatch([]Item{item})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Contains(
+		t,
+		string(files[0].Content),
+		`ifconfig_wg0_aliases="inet 10.64.0.3/32 `+
+			`inet6 fc00:bbbb:bbbb:bb01::3/128"`,
+	)
+	assert.Equal(t, fs.FileMode(0o644), files[0].Mode)
+	assert.Equal(t, "start_if.wg0", files[1].Name)
+	assert.Equal(t, fs.FileMode(0o600), files[1].Mode)
+	assert.Equal(t, "freebsd.wg0.up", files[2].Name)
+	assert.NotContains(t, string(files[0].Content), "$(")
+
+	// The default route halves wait for the endpoint routes of the script.
+	assert.NotContains(t, string(files[0].Content), "0.0.0.0/1")
+	script := string(files[2].Content)
+	assert.Less(
+		t,
+		strings.Index(script, "route -q add -inet6 2a03:1b20:3:f011::a01f"),
+		strings.Index(script, "route -q add -inet -net 0.0.0.0/1"),
+	)
+}
+
+func TestBSDEndpointScript(t *testing.T) {
+	t.Parallel()
+
+	script := string(bsdEndpointScript(
+		"wg0",
+		[]netip.Addr{netip.MustParseAddr("62.3.36.228")},
+		[]netip.Prefix{netip.MustParsePrefix("0.0.0.0/1")},
+	))
+
+	// Deleting the routes first lets the script run again, and keeps the
+	// gateway lookup of a second run from finding the tunnel.
+	assert.Less(
+		t,
+		strings.Index(script, "route -q delete -inet -net 0.0.0.0/1 || true"),
+		strings.Index(script, "route -n get -inet default"),
+	)
+	assert.Less(
+		t,
+		strings.Index(script, "route -q delete -inet 62.3.36.228 || true"),
+		strings.Index(script, "route -n get -inet default"),
+	)
+	assert.Contains(t, script, "set -e\n")
+}
+
+func TestOpenBSD(t *testing.T) {
+	t.Parallel()
+
+	t.Run("full tunnels run their endpoint script", func(t *testing.T) {
+		t.Parallel()
+
+		files, err := NewOpenBSD().FormatBatch(testItems()[:1])
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		assert.Len(t, files, 2)
+		assert.Contains(
+			t,
+			string(files[0].Content),
+			"up\n!/bin/sh /etc/openbsd.wg0.up\n",
+		)
+		assert.Equal(t, "openbsd.wg0.up", files[1].Name)
+		assert.NotContains(t, string(files[0].Content), "$(")
+	})
+
+	t.Run("split tunnels need no endpoint script", func(t *testing.T) {
+		t.Parallel()
+
+		item := testItems()[0]
+		item.Configuration.Peers[0].AllowedIPs = []netip.Prefix{
+			netip.MustParsePrefix("10.0.0.0/8"),
+		}
+
+		files, err := NewOpenBSD().FormatBatch([]Item{item})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		assert.Len(t, files, 1)
+		assert.NotContains(t, string(files[0].Content), "/etc/openbsd.")
+	})
+}
@@ -0,0 +1,301 @@
+package format
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	CatalogHTMLName     = "index.html"
+	CatalogMarkdownName = "README.md"
+)
+
+// CatalogOptions tunes the catalog.
+type CatalogOptions struct {
+	// QR embeds a QR code of every configuration into index.html when set.
+	QR *QR
+	// Generated is the time the catalog says the loads were read at. Zero
+	// leaves it out.
+	Generated time.Time
+}
+
+// Catalog renders an index.html and a README.md listing the configurations
+// of a run by location, with the load and endpoint of their server and
+// links to every file written for them, so users can pick one to download.
+type Catalog struct {
+	options CatalogOptions
+}
+
+// NewCatalog initializes and returns a Catalog.
+func NewCatalog(options CatalogOptions) *Catalog {
+	return &Catalog{options: options}
+}
+
+// catalogRow is a configuration as listed in the catalog.
+type catalogRow struct {
+	Name     string
+	Country  string
+	City     string
+	Load     string
+	load     int
+	Endpoint string
+	Links    []catalogLink
+	QR       htmltemplate.URL
+}
+
+type catalogLink struct {
+	Format string
+	Name   string
+	Href   string
+}
+
+type catalogPage struct {
+	Generated string
+	QR        bool
+	Rows      []catalogRow
+	// Shared links the files written for all configurations at once.
+	Shared []catalogLink
+}
+
+// Files renders the catalog of items, linking the outputs rendered for
+// them. Rows are sorted by country, city and load. The pages are written
+// with mode 0644, except for an index.html with QR codes, which carry the
+// private keys.
+func (c *Catalog) Files(items []Item, outputs []Output) ([]File, error) {
+	page := catalogPage{
+		QR:   c.options.QR != nil,
+		Rows: make([]catalogRow, 0, len(items)),
+	}
+	if !c.options.Generated.IsZero() {
+		page.Generated = c.options.Generated.UTC().Format(time.RFC1123)
+	}
+
+	for _, item := range items {
+		row, err := c.row(item)
+		if err != nil {
+			return nil, err
+		}
+		page.Rows = append(page.Rows, row)
+	}
+
+	for _, output := range outputs {
+		link := catalogLink{
+			Format: output.Format,
+			Name:   output.Name,
+			Href:   (&url.URL{Path: output.Name}).EscapedPath(),
+		}
+
+		i := itemIndex(items, output.Name)
+		if i < 0 {
+			page.Shared = append(page.Shared, link)
+			continue
+		}
+		page.Rows[i].Links = append(page.Rows[i].Links, link)
+	}
+
+	slices.SortStableFunc(page.Rows, func(a catalogRow, b catalogRow) int {
+		return cmp.Or(
+			cmp.Compare(a.Country, b.Country),
+			cmp.Compare(a.City, b.City),
+			cmp.Compare(a.load, b.load),
+		)
+	})
+
+	var html bytes.Buffer
+	if err := catalogHTMLTemplate.Execute(&html, page); err != nil {
+		return nil, fmt.Errorf("render %s: %w", CatalogHTMLName, err)
+	}
+
+	htmlFile := File{Name: CatalogHTMLName, Content: html.Bytes()}
+	if !page.QR {
+		htmlFile.Mode = publicFileMode
+	}
+
+	return []File{
+		htmlFile,
+		{
+			Name:    CatalogMarkdownName,
+			Content: catalogMarkdown(page),
+			Mode:    publicFileMode,
+		},
+	}, nil
+}
+
+func (c *Catalog) row(item Item) (catalogRow, error) {
+	metadata := item.Server.Metadata
+	row := catalogRow{
+		Name:    item.Name,
+		Country: metadata.Country,
+		City:    metadata.City,
+		load:    metadata.Load,
+	}
+
+	if metadata.Load > 0 {
+		row.Load = strconv.Itoa(metadata.Load) + "%"
+	}
+
+	// The endpoint the configuration connects to, which is not necessarily
+	// the server's IPv4 one.
+	for _, peer := range item.Configuration.Peers {
+		if endpoint := peer.EndpointString(); endpoint != "" {
+			row.Endpoint = endpoint
+			break
+		}
+	}
+
+	if c.options.QR == nil {
+		return row, nil
+	}
+
+	png, err := c.options.QR.Format(item)
+	if errors.Is(err, ErrQRTooLarge) {
+		c.options.QR.warnf("leaving out QR code of %s: %v", item.Name, err)
+		return row, nil
+	}
+	if err != nil {
+		return catalogRow{}, fmt.Errorf(
+			"render QR code of %s: %w",
+			item.Name,
+			err,
+		)
+	}
+	row.QR = htmltemplate.URL(
+		"data:image/png;base64," + base64.StdEncoding.EncodeToString(png),
+	)
+
+	return row, nil
+}
+
+// catalogMarkdown renders the page as a Markdown table. Markdown renderers
+// commonly drop data URIs, so QR codes are only part of index.html.
+func catalogMarkdown(page catalogPage) []byte {
+	var sb strings.Builder
+
+	sb.WriteString("# WireGuard configurations\n\n")
+	if page.Generated != "" {
+		fmt.Fprintf(&sb, "Server loads as of %s.\n\n", page.Generated)
+	}
+
+	sb.WriteString("| Country | City | Load | Endpoint | Files |\n")
+	sb.WriteString("|---------|------|------|----------|-------|\n")
+
+	for _, row := range page.Rows {
+		var endpoint string
+		if row.Endpoint != "" {
+			endpoint = "`" + row.Endpoint + "`"
+		}
+
+		fmt.Fprintf(
+			&sb,
+			"| %s | %s | %s | %s | %s |\n",
+			markdownCell(row.Country),
+			markdownCell(row.City),
+			row.Load,
+			endpoint,
+			strings.Join(markdownLinks(row.Links), "<br>"),
+		)
+	}
+
+	if len(page.Shared) > 0 {
+		fmt.Fprintf(
+			&sb,
+			"\nFiles for all configurations: %s\n",
+			strings.Join(markdownLinks(page.Shared), ", "),
+		)
+	}
+
+	return []byte(sb.String())
+}
+
+func markdownLinks(links []catalogLink) []string {
+	result := make([]string, 0, len(links))
+	for _, link := range links {
+		result = append(
+			result,
+			fmt.Sprintf("[%s](%s)", markdownCell(link.Name), link.Href),
+		)
+	}
+	return result
+}
+
+// markdownCell escapes the characters that would end a table cell or start
+// markup.
+func markdownCell(value string) string {
+	replacer := strings.NewReplacer(
+		`\`, `\\`,
+		"|", `\|`,
+		"[", `\[`,
+		"]", `\]`,
+		"*", `\*`,
+		"_", `\_`,
+		"<", "&lt;",
+	)
+	return replacer.Replace(value)
+}
+
+var catalogHTMLTemplate = htmltemplate.Must(
+	htmltemplate.New(CatalogHTMLName).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>WireGuard configurations</title>
+<style>
+body { font-family: system-ui, sans-serif; margin: 2rem; }
+table { border-collapse: collapse; width: 100%; }
+th, td { border-bottom: 1px solid #ddd; padding: 0.5rem; text-align: left; vertical-align: top; }
+td img { width: 10rem; height: 10rem; image-rendering: pixelated; }
+input { margin-bottom: 1rem; padding: 0.5rem; width: 20rem; max-width: 100%; }
+</style>
+</head>
+<body>
+<h1>WireGuard configurations</h1>
+{{- with .Generated }}
+<p>Server loads as of {{ . }}.</p>
+{{- end }}
+<input id="filter" type="search" placeholder="Filter by country or city" aria-label="Filter">
+<table>
+<thead>
+<tr><th>Country</th><th>City</th><th>Load</th><th>Endpoint</th><th>Files</th>{{ if .QR }}<th>QR code</th>{{ end }}</tr>
+</thead>
+<tbody>
+{{- range $row := .Rows }}
+<tr id="{{ .Name }}">
+<td>{{ .Country }}</td>
+<td>{{ .City }}</td>
+<td>{{ .Load }}</td>
+<td><code>{{ .Endpoint }}</code></td>
+<td>{{ range $i, $link := .Links }}{{ if $i }}<br>{{ end }}<a href="{{ $link.Href }}" title="{{ $link.Format }}" download>{{ $link.Name }}</a>{{ end }}</td>
+{{- if $.QR }}
+<td>{{ with .QR }}<img src="{{ . }}" alt="QR code of {{ $row.Name }}">{{ end }}</td>
+{{- end }}
+</tr>
+{{- end }}
+</tbody>
+</table>
+{{- with .Shared }}
+<p>Files for all configurations:
+{{- range $i, $link := . }}{{ if $i }},{{ end }} <a href="{{ $link.Href }}" title="{{ $link.Format }}" download>{{ $link.Name }}</a>{{ end }}</p>
+{{- end }}
+<script>
+document.getElementById("filter").addEventListener("input", (event) => {
+  const query = event.target.value.toLowerCase();
+  for (const row of document.querySelectorAll("tbody tr")) {
+    const text = row.cells[0].textContent + " " + row.cells[1].textContent;
+    row.hidden = !text.toLowerCase().includes(query);
+  }
+});
+</script>
+</body>
+</html>
+`),
+)
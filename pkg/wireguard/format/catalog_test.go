@@ -0,0 +1,70 @@
+package format
+
+import (
+	"io/fs"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCatalog(t *testing.T) {
+	t.Parallel()
+
+	items := testItems()
+	var outputs []Output
+	for _, f := range []Formatter{NewINI(), NewClash(ProxyGroupOptions{})} {
+		files, err := f.FormatBatch(items)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, file := range files {
+			outputs = append(outputs, Output{Format: f.Name(), File: file})
+		}
+	}
+
+	t.Run("pages list the configs by location", func(t *testing.T) {
+		t.Parallel()
+
+		files, err := NewCatalog(CatalogOptions{
+			Generated: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+		}).Files(items, outputs)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, file := range files {
+			assert.Equal(t, fs.FileMode(0o644), file.Mode)
+			assertGolden(
+				t,
+				filepath.Join("testdata", "catalog", file.Name+".golden"),
+				file.Content,
+			)
+		}
+	})
+
+	t.Run("QR codes are inlined", func(t *testing.T) {
+		t.Parallel()
+
+		files, err := NewCatalog(CatalogOptions{
+			QR: NewQR(QROptions{Size: 64}),
+		}).Files(items, outputs)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		html := string(files[0].Content)
+		assert.Equal(t, 2, strings.Count(html, `src="data:image/png;base64,`))
+		assert.NotContains(t, html, "Server loads as of")
+		assert.NotContains(t, string(files[1].Content), "data:")
+		assert.Zero(t, files[0].Mode)
+	})
+}
+
+func TestMarkdownCell(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, `a \| b \_c\_ &lt;d>`, markdownCell("a | b _c_ <d>"))
+}
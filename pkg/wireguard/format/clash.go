@@ -0,0 +1,121 @@
+package format
+
+import (
+	"fmt"
+
+	"github.com/xbnz/wireguard-config-generator/pkg/wireguard"
+)
+
+// Clash renders configurations as the proxies and proxy-groups of a
+// Clash/Mihomo configuration: a wireguard proxy per server and a url-test or
+// fallback group holding all of them.
+type Clash struct {
+	options ProxyGroupOptions
+}
+
+// NewClash initializes and returns a Clash formatter.
+func NewClash(options ProxyGroupOptions) *Clash {
+	return &Clash{options: options.withDefaults()}
+}
+
+func (f *Clash) Name() string { return "clash" }
+
+func (f *Clash) Extension() string { return ".yaml" }
+
+// Format renders a fragment holding a single proxy and its group.
+func (f *Clash) Format(item Item) ([]byte, error) {
+	return f.render([]Item{item})
+}
+
+// FormatBatch writes all proxies and their group into a single clash.yaml.
+func (f *Clash) FormatBatch(items []Item) ([]File, error) {
+	content, err := f.render(items)
+	if err != nil {
+		return nil, err
+	}
+
+	return []File{{Name: f.Name() + f.Extension(), Content: content}}, nil
+}
+
+type clashDocument struct {
+	Proxies     []clashProxy `yaml:"proxies"`
+	ProxyGroups []clashGroup `yaml:"proxy-groups"`
+}
+
+type clashProxy struct {
+	Name                string   `yaml:"name"`
+	Type                string   `yaml:"type"`
+	Server              string   `yaml:"server"`
+	Port                uint16   `yaml:"port"`
+	IP                  string   `yaml:"ip,omitempty"`
+	IPv6                string   `yaml:"ipv6,omitempty"`
+	PrivateKey          string   `yaml:"private-key"`
+	PublicKey           string   `yaml:"public-key"`
+	PreSharedKey        string   `yaml:"pre-shared-key,omitempty"`
+	AllowedIPs          []string `yaml:"allowed-ips"`
+	UDP                 bool     `yaml:"udp"`
+	MTU                 uint16   `yaml:"mtu,omitempty"`
+	PersistentKeepalive uint16   `yaml:"persistent-keepalive,omitempty"`
+	DNS                 []string `yaml:"dns,omitempty"`
+	RemoteDNSResolve    bool     `yaml:"remote-dns-resolve,omitempty"`
+}
+
+type clashGroup struct {
+	Name     string   `yaml:"name"`
+	Type     string   `yaml:"type"`
+	Proxies  []string `yaml:"proxies"`
+	URL      string   `yaml:"url"`
+	Interval int      `yaml:"interval"`
+}
+
+func (f *Clash) render(items []Item) ([]byte, error) {
+	tags := proxyTags(items)
+	document := clashDocument{
+		Proxies: make([]clashProxy, 0, len(items)),
+		ProxyGroups: []clashGroup{{
+			Name:     f.options.Name,
+			Type:     f.options.Type,
+			Proxies:  tags,
+			URL:      f.options.URL,
+			Interval: int(f.options.Interval.Seconds()),
+		}},
+	}
+
+	for i, item := range items {
+		config := item.Configuration
+
+		peer, err := proxyPeer("clash", config)
+		if err != nil {
+			return nil, fmt.Errorf("format %s as clash: %w", item.Name, err)
+		}
+
+		proxy := clashProxy{
+			Name:                tags[i],
+			Type:                "wireguard",
+			Server:              peer.Endpoint.Addr().String(),
+			Port:                peer.Endpoint.Port(),
+			PrivateKey:          config.PrivateKey,
+			PublicKey:           peer.PublicKey,
+			PreSharedKey:        peer.PresharedKey,
+			AllowedIPs:          toStrings(peer.AllowedIPs),
+			UDP:                 true,
+			MTU:                 config.MTU,
+			PersistentKeepalive: peer.PersistentKeepalive,
+			DNS:                 toStrings(config.DNS),
+			RemoteDNSResolve:    len(config.DNS) > 0,
+		}
+
+		// Clash takes a single address per family, without prefix length.
+		v4, v6 := splitPrefixes(config.InterfaceAddresses)
+		if len(v4) > 0 {
+			proxy.IP = v4[0].Addr().String()
+		}
+		if len(v6) > 0 {
+			proxy.IPv6 = v6[0].Addr().String()
+		}
+
+		document.Proxies = append(document.Proxies, proxy)
+	}
+
+	return wireguard.MarshalYAML(document)
+}
@@ -0,0 +1,182 @@
+package format
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/xbnz/wireguard-config-generator/pkg/wireguard"
+)
+
+// BatchDocumentVersion is the version written to batch documents. It only
+// changes when existing fields change meaning or are removed.
+const BatchDocumentVersion = 1
+
+// DocumentOptions tunes the JSON and YAML output.
+type DocumentOptions struct {
+	// Batch writes all configurations of a run into a single document that
+	// also carries the name and server of each configuration.
+	Batch bool
+}
+
+// BatchDocument holds every configuration of a run together with the server
+// it points at.
+type BatchDocument struct {
+	Version        int            `json:"version"        yaml:"version"`
+	Configurations []ItemDocument `json:"configurations" yaml:"configurations"`
+}
+
+// ItemDocument is the machine-readable form of an Item.
+type ItemDocument struct {
+	Name          string                          `json:"name"          yaml:"name"`
+	Server        wireguard.ServerDocument        `json:"server"        yaml:"server"`
+	Configuration wireguard.ConfigurationDocument `json:"configuration" yaml:"configuration"`
+}
+
+// NewBatchDocument initializes and returns the BatchDocument of items.
+func NewBatchDocument(items []Item) BatchDocument {
+	document := BatchDocument{
+		Version:        BatchDocumentVersion,
+		Configurations: make([]ItemDocument, 0, len(items)),
+	}
+
+	for _, item := range items {
+		document.Configurations = append(document.Configurations, ItemDocument{
+			Name:          item.Name,
+			Server:        item.Server.Document(),
+			Configuration: item.Configuration.Document(),
+		})
+	}
+
+	return document
+}
+
+// Items parses the document back into items that can be handed to any
+// formatter.
+func (d BatchDocument) Items() ([]Item, error) {
+	if d.Version != BatchDocumentVersion {
+		return nil, fmt.Errorf(
+			"unsupported batch document version %d",
+			d.Version,
+		)
+	}
+
+	items := make([]Item, 0, len(d.Configurations))
+
+	for i, document := range d.Configurations {
+		config, err := document.Configuration.Configuration()
+		if err != nil {
+			return nil, fmt.Errorf("configuration %d: %w", i, err)
+		}
+
+		server, err := document.Server.Server()
+		if err != nil {
+			return nil, fmt.Errorf("configuration %d: server: %w", i, err)
+		}
+
+		items = append(items, Item{
+			Name:          document.Name,
+			Configuration: config,
+			Server:        server,
+		})
+	}
+
+	return items, nil
+}
+
+// ParseJSONBatch reads a batch document written by the json formatter.
+func ParseJSONBatch(r io.Reader) ([]Item, error) {
+	var document BatchDocument
+	if err := wireguard.UnmarshalJSON(r, &document); err != nil {
+		return nil, err
+	}
+	return document.Items()
+}
+
+// ParseYAMLBatch reads a batch document written by the yaml formatter.
+func ParseYAMLBatch(r io.Reader) ([]Item, error) {
+	var document BatchDocument
+	if err := wireguard.UnmarshalYAML(r, &document); err != nil {
+		return nil, err
+	}
+	return document.Items()
+}
+
+// JSON renders configurations as JSON documents for other tooling.
+type JSON struct {
+	options DocumentOptions
+}
+
+// NewJSON initializes and returns a JSON formatter.
+func NewJSON(options DocumentOptions) *JSON {
+	return &JSON{options: options}
+}
+
+func (f *JSON) Name() string { return "json" }
+
+func (f *JSON) Extension() string { return ".json" }
+
+// Format renders the configuration with Configuration.ToJSONFormat.
+func (f *JSON) Format(item Item) ([]byte, error) {
+	content, err := item.Configuration.ToJSONFormat()
+	if err != nil {
+		return nil, err
+	}
+	return []byte(content), nil
+}
+
+// FormatBatch writes one .json file per configuration, or a single
+// configurations.json batch document.
+func (f *JSON) FormatBatch(items []Item) ([]File, error) {
+	if !f.options.Batch {
+		return Each(f, items, 0)
+	}
+	return batchDocumentFiles(f, items, wireguard.MarshalJSON)
+}
+
+// YAML renders configurations as YAML documents for other tooling.
+type YAML struct {
+	options DocumentOptions
+}
+
+// NewYAML initializes and returns a YAML formatter.
+func NewYAML(options DocumentOptions) *YAML {
+	return &YAML{options: options}
+}
+
+func (f *YAML) Name() string { return "yaml" }
+
+func (f *YAML) Extension() string { return ".yaml" }
+
+// Format renders the configuration with Configuration.ToYAMLFormat.
+func (f *YAML) Format(item Item) ([]byte, error) {
+	content, err := item.Configuration.ToYAMLFormat()
+	if err != nil {
+		return nil, err
+	}
+	return []byte(content), nil
+}
+
+// FormatBatch writes one .yaml file per configuration, or a single
+// configurations.yaml batch document.
+func (f *YAML) FormatBatch(items []Item) ([]File, error) {
+	if !f.options.Batch {
+		return Each(f, items, 0)
+	}
+	return batchDocumentFiles(f, items, wireguard.MarshalYAML)
+}
+
+func batchDocumentFiles(
+	f Formatter,
+	items []Item,
+	marshal func(document any) ([]byte, error),
+) ([]File, error) {
+	content, err := marshal(NewBatchDocument(items))
+	if err != nil {
+		return nil, err
+	}
+
+	return []File{{
+		Name:    "configurations" + f.Extension(),
+		Content: content,
+	}}, nil
+}
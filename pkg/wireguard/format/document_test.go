@@ -0,0 +1,60 @@
+package format
+
+import (
+	"bytes"
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDocument(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		formatter Formatter
+		parse     func(r io.Reader) ([]Item, error)
+	}{
+		{NewJSON(DocumentOptions{Batch: true}), ParseJSONBatch},
+		{NewYAML(DocumentOptions{Batch: true}), ParseYAMLBatch},
+	} {
+		t.Run(tc.formatter.Name()+" batch round trips", func(t *testing.T) {
+			t.Parallel()
+
+			files, err := tc.formatter.FormatBatch(testItems())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			assert.Len(t, files, 1)
+			assertGolden(
+				t,
+				filepath.Join(
+					"testdata",
+					tc.formatter.Name(),
+					"batch",
+					files[0].Name+".golden",
+				),
+				files[0].Content,
+			)
+
+			items, err := tc.parse(bytes.NewReader(files[0].Content))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			assert.Equal(t, testItems(), items)
+		})
+	}
+
+	t.Run("unknown versions are rejected", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := ParseJSONBatch(
+			strings.NewReader(`{"version": 2, "configurations": []}`),
+		)
+		assert.ErrorContains(t, err, "unsupported batch document version 2")
+	})
+}
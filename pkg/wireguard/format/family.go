@@ -0,0 +1,60 @@
+package format
+
+import (
+	"net/netip"
+	"strings"
+)
+
+// splitPrefixes separates prefixes into their IPv4 and IPv6 members.
+func splitPrefixes(prefixes []netip.Prefix) (v4, v6 []netip.Prefix) {
+	for _, prefix := range prefixes {
+		if prefix.Addr().Is4() {
+			v4 = append(v4, prefix)
+			continue
+		}
+		v6 = append(v6, prefix)
+	}
+	return v4, v6
+}
+
+// splitAddrs separates addresses into their IPv4 and IPv6 members.
+func splitAddrs(addrs []netip.Addr) (v4, v6 []netip.Addr) {
+	for _, addr := range addrs {
+		if addr.Unmap().Is4() {
+			v4 = append(v4, addr)
+			continue
+		}
+		v6 = append(v6, addr)
+	}
+	return v4, v6
+}
+
+// hasDefaultRoute reports whether any of the prefixes is a default route.
+func hasDefaultRoute(prefixes []netip.Prefix) bool {
+	for _, prefix := range prefixes {
+		if prefix.Bits() == 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// stringer is satisfied by the netip types the formatters render.
+type stringer interface {
+	String() string
+}
+
+// toStrings renders values with their String method.
+func toStrings[T stringer](values []T) []string {
+	parts := make([]string, 0, len(values))
+	for _, value := range values {
+		parts = append(parts, value.String())
+	}
+	return parts
+}
+
+// joinStrings renders values with their String method and joins them with
+// sep.
+func joinStrings[T stringer](values []T, sep string) string {
+	return strings.Join(toStrings(values), sep)
+}
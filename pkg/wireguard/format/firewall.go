@@ -0,0 +1,74 @@
+package format
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/xbnz/wireguard-config-generator/pkg/wireguard"
+)
+
+// firewallXMLFileMode keeps the private keys inside the fragments away from
+// other users.
+const firewallXMLFileMode = 0o600
+
+// firewallGatewayMaxLen is the longest gateway name OPNsense and pfSense
+// accept.
+const firewallGatewayMaxLen = 32
+
+type firewallGatewayFamily struct {
+	protocol string
+	suffix   string
+}
+
+// firewallGatewayFamilies returns a gateway family for every address family
+// the tunnel has an interface address in.
+func firewallGatewayFamilies(
+	config wireguard.Configuration,
+) []firewallGatewayFamily {
+	v4, v6 := splitPrefixes(config.InterfaceAddresses)
+
+	var families []firewallGatewayFamily
+	if len(v4) > 0 {
+		families = append(families, firewallGatewayFamily{"inet", ""})
+	}
+	if len(v6) > 0 {
+		families = append(families, firewallGatewayFamily{"inet6", "_V6"})
+	}
+
+	return families
+}
+
+// firewallGatewayName returns the gateway name of a tunnel, which may only
+// contain letters, digits and underscores.
+func firewallGatewayName(item Item, family firewallGatewayFamily) string {
+	name := uciName(
+		"WG_"+strings.ToUpper(item.Name),
+		firewallGatewayMaxLen-len(family.suffix),
+	)
+	return name + family.suffix
+}
+
+func firewallPeerName(item Item, peer int) string {
+	if peer == 0 {
+		return item.Name
+	}
+	return fmt.Sprintf("%s_%d", item.Name, peer)
+}
+
+func optionalUint[T uint16 | uint32](value T) string {
+	if value == 0 {
+		return ""
+	}
+	return strconv.FormatUint(uint64(value), 10)
+}
+
+func marshalFirewallXML(document any) ([]byte, error) {
+	content, err := xml.MarshalIndent(document, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("marshal xml: %w", err)
+	}
+
+	return append([]byte(xml.Header), append(content, '\n')...), nil
+}
@@ -0,0 +1,102 @@
+package format
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFirewallXML(t *testing.T) {
+	t.Parallel()
+
+	t.Run("opnsense uuids survive renumbering", func(t *testing.T) {
+		t.Parallel()
+
+		items := testItems()
+
+		batch, err := NewOPNsense().FormatBatch(items)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// The first item sorts second by identity. On its own it becomes
+		// instance 0 but keeps its UUID.
+		single, err := NewOPNsense().Format(items[0])
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		uuid := deterministicUUID("opnsense", "server", identity(items[0]))
+		assert.Contains(t, string(batch[0].Content), `uuid="`+uuid+`"`)
+		assert.Contains(t, string(single), `<server uuid="`+uuid+`">`)
+		assert.Contains(t, string(batch[0].Content), "<instance>1</instance>")
+		assert.Contains(t, string(single), "<instance>0</instance>")
+	})
+
+	t.Run("opnsense uuids follow the server", func(t *testing.T) {
+		t.Parallel()
+
+		item := testItems()[0]
+		renumbered := item
+		renumbered.Name = "nordvpn_7"
+
+		uuid := deterministicUUID("opnsense", "server", identity(item))
+		single, err := NewOPNsense().Format(renumbered)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		assert.Contains(t, string(single), `<server uuid="`+uuid+`">`)
+	})
+
+	for _, f := range []Formatter{NewOPNsense(), NewPfSense()} {
+		t.Run(f.Name()+" tunnels are numbered by server", func(t *testing.T) {
+			t.Parallel()
+
+			items := testItems()
+			reordered := slices.Clone(items)
+			slices.Reverse(reordered)
+
+			batch, err := f.FormatBatch(items)
+			if err != nil {
+				t.Fatal(err)
+			}
+			reorderedBatch, err := f.FormatBatch(reordered)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			assert.Equal(
+				t,
+				string(batch[0].Content),
+				string(reorderedBatch[0].Content),
+			)
+		})
+	}
+
+	t.Run("malformed private keys are reported", func(t *testing.T) {
+		t.Parallel()
+
+		item := testItems()[0]
+		item.Configuration.PrivateKey = "private_key"
+
+		_, err := NewPfSense().Format(item)
+		assert.ErrorContains(t, err, "format nordvpn_0 as pfsense")
+	})
+
+	t.Run("gateway names fit the limit", func(t *testing.T) {
+		t.Parallel()
+
+		item := testItems()[0]
+		item.Name = "nordvpn-united-kingdom-london-1234"
+
+		name := firewallGatewayName(
+			item,
+			firewallGatewayFamily{"inet6", "_V6"},
+		)
+
+		assert.Equal(t, "WG_NORDVPN_UNITED_KINGDOM_LON_V6", name)
+		assert.Len(t, name, firewallGatewayMaxLen)
+	})
+}
@@ -0,0 +1,192 @@
+package format
+
+import (
+	"fmt"
+	"io/fs"
+	"slices"
+	"strings"
+
+	"github.com/xbnz/wireguard-config-generator/pkg/wireguard"
+)
+
+// Item is a generated configuration together with the base name its output
+// files are written under. Server describes the server the configuration
+// points at and is empty for configurations that were not generated from a
+// server list.
+type Item struct {
+	Name          string
+	Configuration wireguard.Configuration
+	Server        wireguard.Server
+}
+
+// NewItem creates a new Item with the provided name and configuration.
+func NewItem(name string, configuration wireguard.Configuration) Item {
+	return Item{Name: name, Configuration: configuration}
+}
+
+const (
+	// DefaultFileMode is the mode of files with a zero Mode. Most files hold
+	// private keys, so they are readable by their owner only.
+	DefaultFileMode fs.FileMode = 0o600
+
+	// publicFileMode is for files without secrets that other users or
+	// services need to read.
+	publicFileMode fs.FileMode = 0o644
+)
+
+// File is a rendered output file. Name is relative to the output directory
+// and a zero Mode stands for DefaultFileMode.
+type File struct {
+	Name    string
+	Content []byte
+	Mode    fs.FileMode
+}
+
+// Formatter renders configurations into a target format.
+type Formatter interface {
+	// Name is the value that selects the formatter in --format.
+	Name() string
+	// Extension is the file extension, including the leading dot, of the
+	// file written for each configuration.
+	Extension() string
+	// Format renders a single configuration.
+	Format(item Item) ([]byte, error)
+	// FormatBatch renders all configurations of a run into output files.
+	FormatBatch(items []Item) ([]File, error)
+}
+
+// Each renders every item on its own with f.Format and names each file after
+// its item and the formatter's extension. Formatters without batch-level
+// output use it to implement FormatBatch.
+func Each(f Formatter, items []Item, mode fs.FileMode) ([]File, error) {
+	files := make([]File, 0, len(items))
+
+	for _, item := range items {
+		content, err := f.Format(item)
+		if err != nil {
+			return nil, fmt.Errorf(
+				"format %s as %s: %w",
+				item.Name,
+				f.Name(),
+				err,
+			)
+		}
+
+		files = append(files, File{
+			Name:    item.Name + f.Extension(),
+			Content: content,
+			Mode:    mode,
+		})
+	}
+
+	return files, nil
+}
+
+// itemIndex returns the index of the item a file was rendered for, going by
+// file names starting with the item's name like Each writes them, or -1 for
+// files shared by all items.
+func itemIndex(items []Item, name string) int {
+	return slices.IndexFunc(items, func(item Item) bool {
+		return strings.HasPrefix(name, item.Name+".") ||
+			strings.HasPrefix(name, item.Name+"/")
+	})
+}
+
+// checkEndpointAddrs rejects configurations with a peer whose endpoint names
+// a host, for targets that take IP addresses only.
+func checkEndpointAddrs(config wireguard.Configuration) error {
+	for i, peer := range config.Peers {
+		if peer.EndpointHost != "" {
+			return fmt.Errorf(
+				"endpoint %s of peer %d is a host name, not an address",
+				peer.EndpointHost,
+				i,
+			)
+		}
+	}
+	return nil
+}
+
+// checkItemEndpointAddrs runs checkEndpointAddrs on every item, for
+// FormatBatch implementations that do not go through Format.
+func checkItemEndpointAddrs(f Formatter, items []Item) error {
+	for _, item := range items {
+		if err := checkEndpointAddrs(item.Configuration); err != nil {
+			return fmt.Errorf(
+				"format %s as %s: %w",
+				item.Name,
+				f.Name(),
+				err,
+			)
+		}
+	}
+	return nil
+}
+
+// Registry holds the formatters available to a run, keyed by name.
+type Registry struct {
+	formatters map[string]Formatter
+}
+
+// NewRegistry initializes and returns a Registry holding the provided
+// formatters.
+func NewRegistry(formatters ...Formatter) (*Registry, error) {
+	registry := &Registry{formatters: map[string]Formatter{}}
+
+	for _, f := range formatters {
+		if err := registry.Register(f); err != nil {
+			return nil, err
+		}
+	}
+
+	return registry, nil
+}
+
+// Register adds a formatter to the registry. Names must be unique.
+func (r *Registry) Register(f Formatter) error {
+	if _, ok := r.formatters[f.Name()]; ok {
+		return fmt.Errorf("formatter %q is already registered", f.Name())
+	}
+
+	r.formatters[f.Name()] = f
+	return nil
+}
+
+// Get returns the formatter registered under name.
+func (r *Registry) Get(name string) (Formatter, bool) {
+	f, ok := r.formatters[name]
+	return f, ok
+}
+
+// Names returns the names of all registered formatters in sorted order.
+func (r *Registry) Names() []string {
+	names := make([]string, 0, len(r.formatters))
+	for name := range r.formatters {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// Select returns the formatters for the given names in the given order,
+// skipping repeated names.
+func (r *Registry) Select(names []string) ([]Formatter, error) {
+	var selected []Formatter
+
+	for _, name := range names {
+		f, ok := r.Get(name)
+		if !ok {
+			return nil, fmt.Errorf(
+				"unknown format %q (available: %s)",
+				name,
+				strings.Join(r.Names(), ", "),
+			)
+		}
+
+		if !slices.Contains(selected, f) {
+			selected = append(selected, f)
+		}
+	}
+
+	return selected, nil
+}
@@ -0,0 +1,251 @@
+package format
+
+import (
+	"flag"
+	"math/rand/v2"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/xbnz/wireguard-config-generator/pkg/wireguard"
+)
+
+var update = flag.Bool("update", false, "update golden files")
+
+func testItems() []Item {
+	first := wireguard.NewConfiguration(
+		"OEvHuuMpALNf7ZZkzUSGbT8vkj89aHrhLyqZlIn4rPU=",
+		[]netip.Prefix{netip.MustParsePrefix("10.5.0.2/32")},
+		[]netip.Addr{netip.MustParseAddr("103.86.96.100")},
+		[]wireguard.PeerConfig{
+			wireguard.NewPeerConfig(
+				"qIhtTW9K4iXWFo5Q4dOPdXg8/xubXr9yEGoN55D8xnA=",
+				netip.MustParseAddrPort("62.3.36.228:51820"),
+				[]netip.Prefix{netip.MustParsePrefix("0.0.0.0/0")},
+				25,
+			),
+		},
+	)
+
+	second := wireguard.NewConfiguration(
+		"OEvHuuMpALNf7ZZkzUSGbT8vkj89aHrhLyqZlIn4rPU=",
+		[]netip.Prefix{
+			netip.MustParsePrefix("10.64.0.2/32"),
+			netip.MustParsePrefix("fc00:bbbb:bbbb:bb01::2/128"),
+		},
+		[]netip.Addr{netip.MustParseAddr("10.64.0.1")},
+		[]wireguard.PeerConfig{
+			wireguard.NewPeerConfig(
+				"3QnSY6ObZk8KnDrHNyT4H3dBf7sNfFMx8Yl2YpXg1W0=",
+				netip.MustParseAddrPort("[2a03:1b20:3:f011::a01f]:51820"),
+				[]netip.Prefix{
+					netip.MustParsePrefix("0.0.0.0/0"),
+					netip.MustParsePrefix("::/0"),
+				},
+				0,
+			),
+		},
+	)
+	second.MTU = 1420
+	second.Peers[0].PresharedKey = "FpCyhws9cxwWoV4xELtfJvjJN+zQVRPISllRWgeopVE="
+
+	items := []Item{
+		NewItem("nordvpn_0", first),
+		NewItem("mullvad_1", second),
+	}
+
+	items[0].Server = wireguard.NewServer(
+		first.Peers[0].PublicKey,
+		first.Peers[0].Endpoint,
+		netip.AddrPort{},
+	)
+	items[0].Server.Metadata = wireguard.ServerMetadata{
+		Hostname:    "de1000.nordvpn.com",
+		Country:     "Germany",
+		CountryCode: "DE",
+		City:        "Frankfurt",
+		Load:        17,
+	}
+
+	items[1].Server = wireguard.NewServer(
+		second.Peers[0].PublicKey,
+		netip.MustParseAddrPort("185.213.154.68:51820"),
+		second.Peers[0].Endpoint,
+	)
+	items[1].Server.Metadata = wireguard.ServerMetadata{
+		Hostname:    "se-got-wg-001",
+		Country:     "Sweden",
+		CountryCode: "se",
+		City:        "Gothenburg",
+	}
+
+	return items
+}
+
+// goldenFormatters lists the formatters covered by TestFormatters_Golden.
+func goldenFormatters() []Formatter {
+	return []Formatter{
+		NewINI(),
+		NewIPC(),
+		NewNetworkManager(NetworkManagerOptions{}),
+		NewNetworkd(NetworkdOptions{}),
+		NewOpenWrt(OpenWrtOptions{}),
+		NewMikroTik(MikroTikOptions{}),
+		NewOPNsense(),
+		NewPfSense(),
+		NewGluetun(GluetunOptions{}),
+		NewKubernetes(KubernetesOptions{}),
+		NewJSON(DocumentOptions{}),
+		NewYAML(DocumentOptions{}),
+		NewWireproxy(WireproxyOptions{
+			SocksPort: DefaultWireproxySocksPort,
+		}),
+		NewSingBox(ProxyGroupOptions{}),
+		NewClash(ProxyGroupOptions{}),
+		NewXray(ProxyGroupOptions{}),
+		NewNixOS(NixOSOptions{}),
+		NewNetplan(NetplanOptions{}),
+		NewVyOS(VyOSOptions{}),
+		NewOpenBSD(),
+		NewFreeBSD(),
+		NewAmneziaWG(AmneziaWGOptions{
+			Headers: true,
+			Rand:    rand.New(rand.NewPCG(1, 2)),
+		}),
+	}
+}
+
+func TestFormatters_Golden(t *testing.T) {
+	t.Parallel()
+
+	for _, f := range goldenFormatters() {
+		t.Run(f.Name(), func(t *testing.T) {
+			t.Parallel()
+
+			files, err := f.FormatBatch(testItems())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			assert.NotEmpty(t, files)
+
+			for _, file := range files {
+				assertGolden(
+					t,
+					filepath.Join("testdata", f.Name(), file.Name+".golden"),
+					file.Content,
+				)
+			}
+		})
+	}
+}
+
+func TestFormatters_HostEndpoints(t *testing.T) {
+	t.Parallel()
+
+	// Formats that take the endpoint as a string keep host names, the others
+	// need an address to put into their fields or routes.
+	keepsHosts := []string{
+		"ini",
+		"networkd",
+		"kubernetes",
+		"json",
+		"yaml",
+		"wireproxy",
+		"nixos",
+		"amneziawg",
+	}
+
+	for _, f := range goldenFormatters() {
+		t.Run(f.Name(), func(t *testing.T) {
+			t.Parallel()
+
+			items := testItems()[:1]
+			peer := &items[0].Configuration.Peers[0]
+			peer.Endpoint = netip.AddrPort{}
+			peer.EndpointHost = "vpn.example:51820"
+
+			files, err := f.FormatBatch(items)
+
+			if !slices.Contains(keepsHosts, f.Name()) {
+				assert.ErrorContains(t, err, "vpn.example:51820 of peer 0")
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+			assert.True(t, slices.ContainsFunc(files, func(file File) bool {
+				return strings.Contains(
+					string(file.Content),
+					"vpn.example:51820",
+				)
+			}))
+		})
+	}
+}
+
+func TestRegistry(t *testing.T) {
+	t.Parallel()
+
+	t.Run("formatters are selected in the requested order", func(t *testing.T) {
+		t.Parallel()
+
+		registry, err := NewRegistry(NewINI(), NewIPC())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		selected, err := registry.Select([]string{"ipc", "ini", "ipc"})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		assert.Equal(t, []Formatter{NewIPC(), NewINI()}, selected)
+		assert.Equal(t, []string{"ini", "ipc"}, registry.Names())
+	})
+
+	t.Run("unknown formats list the available ones", func(t *testing.T) {
+		t.Parallel()
+
+		registry, err := NewRegistry(NewINI())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_, err = registry.Select([]string{"yaml"})
+		assert.ErrorContains(t, err, `unknown format "yaml" (available: ini)`)
+	})
+
+	t.Run("names must be unique", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := NewRegistry(NewINI(), NewINI())
+		assert.ErrorContains(t, err, `formatter "ini" is already registered`)
+	})
+}
+
+func assertGolden(t *testing.T, path string, content []byte) {
+	t.Helper()
+
+	if *update {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, content, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	golden, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, string(golden), string(content))
+}
@@ -0,0 +1,180 @@
+package format
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	freeBSDRCConfName = "freebsd.rc.conf"
+
+	// The start_if files hold the private keys.
+	freeBSDStartIfFileMode = 0o600
+)
+
+// FreeBSD renders configurations as rc.conf settings for the native wg(4)
+// driver. ifconfig(8) cannot set WireGuard keys, so each interface also gets
+// an /etc/start_if.wgN script, which rc.d/netif sources before configuring
+// the interface, loading them with wg(8) setconf.
+type FreeBSD struct{}
+
+// NewFreeBSD initializes and returns a FreeBSD formatter.
+func NewFreeBSD() *FreeBSD {
+	return &FreeBSD{}
+}
+
+func (f *FreeBSD) Name() string { return "freebsd" }
+
+func (f *FreeBSD) Extension() string { return ".rc.conf" }
+
+// Format renders the rc.conf settings of a single configuration on wg0.
+func (f *FreeBSD) Format(item Item) ([]byte, error) {
+	if err := checkEndpointAddrs(item.Configuration); err != nil {
+		return nil, err
+	}
+	return []byte(f.rcConf([]Item{item})), nil
+}
+
+// FormatBatch writes a single freebsd.rc.conf holding the settings of all
+// interfaces, numbered in item order, plus a start_if.wgN script per
+// interface. Interfaces routing a default route through the tunnel also get
+// an endpoint script, as rc.conf cannot look up the existing gateway. The
+// script adds the default route halves itself after the endpoint routes, so
+// until it runs from rc.local the tunnel only carries its other routes and
+// handshakes never go into the tunnel.
+func (f *FreeBSD) FormatBatch(items []Item) ([]File, error) {
+	if err := checkItemEndpointAddrs(f, items); err != nil {
+		return nil, err
+	}
+
+	files := []File{{
+		Name:    freeBSDRCConfName,
+		Content: []byte(f.rcConf(items)),
+		Mode:    publicFileMode,
+	}}
+
+	for i, item := range items {
+		name := fmt.Sprintf("wg%d", i)
+
+		var sb strings.Builder
+		fmt.Fprintf(
+			&sb,
+			"# Sourced by rc.d/netif before %s is configured.\n",
+			name,
+		)
+		fmt.Fprintf(&sb, "wg setconf %s /dev/stdin <<'EOF'\n", name)
+		sb.WriteString(wgSetconf(item.Configuration))
+		fmt.Fprintf(&sb, "EOF\n")
+
+		files = append(files, File{
+			Name:    "start_if." + name,
+			Content: []byte(sb.String()),
+			Mode:    freeBSDStartIfFileMode,
+		})
+
+		_, halves, bypass := bsdRoutes(item.Configuration)
+		if len(halves) > 0 || len(bypass) > 0 {
+			files = append(files, File{
+				Name:    bsdEndpointScriptName(f.Name(), name),
+				Content: bsdEndpointScript(name, bypass, halves),
+			})
+		}
+	}
+
+	return files, nil
+}
+
+func (f *FreeBSD) rcConf(items []Item) string {
+	var (
+		sb         strings.Builder
+		interfaces []string
+		routesV4   []string
+		routesV6   []string
+	)
+
+	for i, item := range items {
+		config := item.Configuration
+		name := fmt.Sprintf("wg%d", i)
+		interfaces = append(interfaces, name)
+
+		fmt.Fprintf(&sb, "ifconfig_%s_descr=%q\n", name, item.Name)
+
+		// The first address of each family goes into the main settings,
+		// every other one is an alias.
+		addressesV4, addressesV6 := splitPrefixes(config.InterfaceAddresses)
+		settings := []string{}
+		if len(addressesV4) > 0 {
+			settings = append(settings, "inet "+addressesV4[0].String())
+		}
+		if config.MTU > 0 {
+			settings = append(settings, fmt.Sprintf("mtu %d", config.MTU))
+		}
+		settings = append(settings, "up")
+		fmt.Fprintf(
+			&sb,
+			"ifconfig_%s=%q\n",
+			name,
+			strings.Join(settings, " "),
+		)
+		if len(addressesV6) > 0 {
+			fmt.Fprintf(
+				&sb,
+				"ifconfig_%s_ipv6=%q\n",
+				name,
+				"inet6 "+addressesV6[0].String(),
+			)
+		}
+
+		var aliases []string
+		for _, address := range addressesV4[min(1, len(addressesV4)):] {
+			aliases = append(aliases, "inet "+address.String())
+		}
+		for _, address := range addressesV6[min(1, len(addressesV6)):] {
+			aliases = append(aliases, "inet6 "+address.String())
+		}
+		if len(aliases) > 0 {
+			fmt.Fprintf(
+				&sb,
+				"ifconfig_%s_aliases=%q\n",
+				name,
+				strings.Join(aliases, " "),
+			)
+		}
+
+		routes, _, _ := bsdRoutes(config)
+		for j, prefix := range routes {
+			route := fmt.Sprintf("%s_%d", name, j)
+			value := fmt.Sprintf("-net %s -interface %s", prefix, name)
+			if prefix.Addr().Is4() {
+				routesV4 = append(routesV4, route)
+				fmt.Fprintf(&sb, "route_%s=%q\n", route, value)
+				continue
+			}
+			routesV6 = append(routesV6, route)
+			fmt.Fprintf(&sb, "ipv6_route_%s=%q\n", route, value)
+		}
+	}
+
+	var header strings.Builder
+	fmt.Fprintf(
+		&header,
+		"cloned_interfaces=\"${cloned_interfaces} %s\"\n",
+		strings.Join(interfaces, " "),
+	)
+	if len(routesV4) > 0 {
+		fmt.Fprintf(
+			&header,
+			"static_routes=\"${static_routes} %s\"\n",
+			strings.Join(routesV4, " "),
+		)
+	}
+	if len(routesV6) > 0 {
+		fmt.Fprintf(
+			&header,
+			"ipv6_static_routes=\"${ipv6_static_routes} %s\"\n",
+			strings.Join(routesV6, " "),
+		)
+	}
+
+	return header.String() + sb.String()
+}
@@ -0,0 +1,240 @@
+package format
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/netip"
+	"strings"
+)
+
+// ErrNoItems is returned by formatters that bundle all configurations into
+// shared files when there is nothing to bundle.
+var ErrNoItems = errors.New("no configurations to bundle")
+
+// GluetunOptions tunes the Gluetun output.
+type GluetunOptions struct {
+	// Compose renders docker-compose environment: blocks instead of .env
+	// files.
+	Compose bool
+	// Bundle writes a single environment for the provider together with a
+	// servers.json holding every server of the run, instead of one custom
+	// provider environment per configuration.
+	Bundle bool
+	// Provider is the Gluetun provider name the bundle is written for.
+	Provider string
+	// Timestamp is the Unix time recorded in servers.json. Gluetun prefers
+	// the server list with the newest timestamp.
+	Timestamp int64
+}
+
+// Gluetun renders configurations as environments for the Gluetun VPN
+// client container.
+type Gluetun struct {
+	options GluetunOptions
+}
+
+// NewGluetun initializes and returns a Gluetun formatter.
+func NewGluetun(options GluetunOptions) *Gluetun {
+	return &Gluetun{options: options}
+}
+
+func (f *Gluetun) Name() string { return "gluetun" }
+
+func (f *Gluetun) Extension() string {
+	if f.options.Compose {
+		return ".compose.yml"
+	}
+	return ".env"
+}
+
+// Format renders the environment of a custom provider connecting to the
+// configuration's first peer.
+func (f *Gluetun) Format(item Item) ([]byte, error) {
+	if err := checkEndpointAddrs(item.Configuration); err != nil {
+		return nil, err
+	}
+
+	config := item.Configuration
+	if len(config.Peers) == 0 {
+		return nil, errors.New("gluetun needs a peer")
+	}
+	peer := config.Peers[0]
+	if !peer.Endpoint.IsValid() {
+		return nil, errors.New("gluetun needs a peer endpoint")
+	}
+
+	env := []gluetunVariable{
+		{"VPN_SERVICE_PROVIDER", "custom"},
+		{"VPN_TYPE", "wireguard"},
+		{"VPN_ENDPOINT_IP", peer.Endpoint.Addr().String()},
+		{"VPN_ENDPOINT_PORT", fmt.Sprint(peer.Endpoint.Port())},
+		{"WIREGUARD_PUBLIC_KEY", peer.PublicKey},
+		{"WIREGUARD_PRIVATE_KEY", config.PrivateKey},
+	}
+	if peer.PresharedKey != "" {
+		env = append(
+			env,
+			gluetunVariable{"WIREGUARD_PRESHARED_KEY", peer.PresharedKey},
+		)
+	}
+	env = append(env, f.tunnelVariables(item)...)
+
+	return []byte(f.render(env)), nil
+}
+
+// FormatBatch writes one environment per configuration, or the bundle of a
+// single environment and servers.json.
+func (f *Gluetun) FormatBatch(items []Item) ([]File, error) {
+	if !f.options.Bundle {
+		return Each(f, items, 0)
+	}
+
+	if len(items) == 0 {
+		return nil, ErrNoItems
+	}
+	if err := checkItemEndpointAddrs(f, items); err != nil {
+		return nil, err
+	}
+
+	// Providers hand out one key and address for all of their servers, so
+	// the first configuration speaks for the whole bundle.
+	env := []gluetunVariable{
+		{"VPN_SERVICE_PROVIDER", f.options.Provider},
+		{"VPN_TYPE", "wireguard"},
+		{"WIREGUARD_PRIVATE_KEY", items[0].Configuration.PrivateKey},
+	}
+	env = append(env, f.tunnelVariables(items[0])...)
+
+	servers, err := f.servers(items)
+	if err != nil {
+		return nil, err
+	}
+
+	return []File{
+		{Name: "gluetun" + f.Extension(), Content: []byte(f.render(env))},
+		{Name: "servers.json", Content: servers, Mode: publicFileMode},
+	}, nil
+}
+
+type gluetunVariable struct {
+	key   string
+	value string
+}
+
+// tunnelVariables returns the variables describing the tunnel itself, which
+// are the same for custom and bundled providers.
+func (f *Gluetun) tunnelVariables(item Item) []gluetunVariable {
+	config := item.Configuration
+
+	env := []gluetunVariable{
+		{"WIREGUARD_ADDRESSES", joinStrings(config.InterfaceAddresses, ",")},
+	}
+
+	if len(config.Peers) > 0 {
+		peer := config.Peers[0]
+
+		if !config.IsFullTunnel() {
+			env = append(env, gluetunVariable{
+				"WIREGUARD_ALLOWED_IPS",
+				joinStrings(peer.AllowedIPs, ","),
+			})
+		}
+		if peer.PersistentKeepalive > 0 {
+			env = append(env, gluetunVariable{
+				"WIREGUARD_PERSISTENT_KEEPALIVE_INTERVAL",
+				fmt.Sprintf("%ds", peer.PersistentKeepalive),
+			})
+		}
+	}
+
+	if config.MTU > 0 {
+		env = append(env, gluetunVariable{
+			"WIREGUARD_MTU",
+			fmt.Sprint(config.MTU),
+		})
+	}
+
+	return env
+}
+
+func (f *Gluetun) render(env []gluetunVariable) string {
+	var sb strings.Builder
+
+	if f.options.Compose {
+		sb.WriteString("environment:\n")
+	}
+
+	for _, variable := range env {
+		if f.options.Compose {
+			fmt.Fprintf(&sb, "  - %s=%s\n", variable.key, variable.value)
+			continue
+		}
+		fmt.Fprintf(&sb, "%s=%s\n", variable.key, variable.value)
+	}
+
+	return sb.String()
+}
+
+type gluetunServer struct {
+	VPN       string       `json:"vpn"`
+	Country   string       `json:"country,omitempty"`
+	City      string       `json:"city,omitempty"`
+	Hostname  string       `json:"hostname,omitempty"`
+	PublicKey string       `json:"wgpubkey"`
+	IPs       []netip.Addr `json:"ips"`
+}
+
+type gluetunProvider struct {
+	Version   int             `json:"version"`
+	Timestamp int64           `json:"timestamp"`
+	Servers   []gluetunServer `json:"servers"`
+}
+
+// servers renders the servers of all items in Gluetun's servers.json
+// layout, keyed by provider.
+func (f *Gluetun) servers(items []Item) ([]byte, error) {
+	provider := gluetunProvider{
+		Version:   1,
+		Timestamp: f.options.Timestamp,
+		Servers:   make([]gluetunServer, 0, len(items)),
+	}
+
+	for _, item := range items {
+		server := item.Server
+		if server.PublicKey == "" && len(item.Configuration.Peers) > 0 {
+			peer := item.Configuration.Peers[0]
+			server.PublicKey = peer.PublicKey
+			server.Endpoint = peer.Endpoint
+		}
+
+		var ips []netip.Addr
+		for _, endpoint := range []netip.AddrPort{
+			server.Endpoint,
+			server.EndpointV6,
+		} {
+			if endpoint.IsValid() {
+				ips = append(ips, endpoint.Addr())
+			}
+		}
+
+		provider.Servers = append(provider.Servers, gluetunServer{
+			VPN:       "wireguard",
+			Country:   server.Metadata.Country,
+			City:      server.Metadata.City,
+			Hostname:  server.Metadata.Hostname,
+			PublicKey: server.PublicKey,
+			IPs:       ips,
+		})
+	}
+
+	content, err := json.MarshalIndent(map[string]any{
+		"version":          1,
+		f.options.Provider: provider,
+	}, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("marshal gluetun servers: %w", err)
+	}
+
+	return append(content, '\n'), nil
+}
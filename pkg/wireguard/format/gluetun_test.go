@@ -0,0 +1,77 @@
+package format
+
+import (
+	"encoding/json"
+	"io/fs"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGluetun(t *testing.T) {
+	t.Parallel()
+
+	t.Run("compose blocks list the variables", func(t *testing.T) {
+		t.Parallel()
+
+		files, err := NewGluetun(GluetunOptions{Compose: true}).
+			FormatBatch(testItems()[:1])
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		assert.Equal(t, "nordvpn_0.compose.yml", files[0].Name)
+		assert.Equal(
+			t,
+			"environment:\n"+
+				"  - VPN_SERVICE_PROVIDER=custom\n"+
+				"  - VPN_TYPE=wireguard\n"+
+				"  - VPN_ENDPOINT_IP=62.3.36.228\n"+
+				"  - VPN_ENDPOINT_PORT=51820\n"+
+				"  - WIREGUARD_PUBLIC_KEY=qIhtTW9K4iXWFo5Q4dOPdXg8/xubXr9yEGoN55D8xnA=\n"+
+				"  - WIREGUARD_PRIVATE_KEY=OEvHuuMpALNf7ZZkzUSGbT8vkj89aHrhLyqZlIn4rPU=\n"+
+				"  - WIREGUARD_ADDRESSES=10.5.0.2/32\n"+
+				"  - WIREGUARD_PERSISTENT_KEEPALIVE_INTERVAL=25s\n",
+			string(files[0].Content),
+		)
+	})
+
+	t.Run("bundles write the server list", func(t *testing.T) {
+		t.Parallel()
+
+		files, err := NewGluetun(GluetunOptions{
+			Bundle:    true,
+			Provider:  "nordvpn",
+			Timestamp: 1700000000,
+		}).FormatBatch(testItems())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		assert.Len(t, files, 2)
+		assert.Equal(t, "gluetun.env", files[0].Name)
+		assert.Contains(
+			t,
+			string(files[0].Content),
+			"VPN_SERVICE_PROVIDER=nordvpn\n",
+		)
+		assert.Equal(t, "servers.json", files[1].Name)
+		assert.Equal(t, fs.FileMode(0o644), files[1].Mode)
+		assertGolden(
+			t,
+			"testdata/gluetun/bundle/servers.json.golden",
+			files[1].Content,
+		)
+
+		var decoded map[string]json.RawMessage
+		assert.NoError(t, json.Unmarshal(files[1].Content, &decoded))
+		assert.Contains(t, decoded, "nordvpn")
+	})
+
+	t.Run("bundles need configurations", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := NewGluetun(GluetunOptions{Bundle: true}).FormatBatch(nil)
+		assert.ErrorIs(t, err, ErrNoItems)
+	})
+}
@@ -0,0 +1,90 @@
+package format
+
+import (
+	"cmp"
+	"crypto/sha1" //nolint:gosec // required by RFC 9562 name-based UUIDs
+	"encoding/hex"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// uuidNamespace is the name-based UUID of this project's repository URL. It
+// namespaces every UUID the formatters derive, so they never collide with
+// UUIDs other tools derive from the same names.
+const uuidNamespace = "55d4b647ddba55ff98c2c36b0fe1bb31"
+
+// identity returns the stable name of an item: the public key of the server
+// it points at, or its hostname when there is no peer. Item names are
+// numbered in the provider's order, so they only serve as the last resort.
+func identity(item Item) string {
+	switch {
+	case len(item.Configuration.Peers) > 0:
+		return item.Configuration.Peers[0].PublicKey
+	case item.Server.Metadata.Hostname != "":
+		return item.Server.Metadata.Hostname
+	default:
+		return item.Name
+	}
+}
+
+// sortedByIdentity returns a copy of items ordered by identity, for formats
+// that can only number their entries, so that a reordered server list keeps
+// the numbers of the servers in it.
+func sortedByIdentity(items []Item) []Item {
+	sorted := slices.Clone(items)
+	slices.SortStableFunc(sorted, func(a Item, b Item) int {
+		return cmp.Compare(identity(a), identity(b))
+	})
+	return sorted
+}
+
+// deterministicUUID derives a version 5 UUID from the given name parts, so
+// regenerating the same configuration yields the same UUID.
+func deterministicUUID(parts ...string) string {
+	namespace, err := hex.DecodeString(uuidNamespace)
+	if err != nil {
+		panic(err)
+	}
+
+	hash := sha1.New() //nolint:gosec // required by RFC 9562 name-based UUIDs
+	hash.Write(namespace)
+	hash.Write([]byte(strings.Join(parts, "\x00")))
+	sum := hash.Sum(nil)
+
+	sum[6] = (sum[6] & 0x0f) | 0x50
+	sum[8] = (sum[8] & 0x3f) | 0x80
+
+	return fmt.Sprintf(
+		"%x-%x-%x-%x-%x",
+		sum[0:4],
+		sum[4:6],
+		sum[6:8],
+		sum[8:10],
+		sum[10:16],
+	)
+}
+
+// interfaceName turns name into a network interface name of at most maxLen
+// characters, replacing everything but letters, digits, dashes and
+// underscores.
+func interfaceName(name string, maxLen int) string {
+	var sb strings.Builder
+
+	for _, r := range name {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9',
+			r == '-', r == '_':
+			sb.WriteRune(r)
+		default:
+			sb.WriteRune('_')
+		}
+	}
+
+	result := sb.String()
+	if len(result) > maxLen {
+		result = result[:maxLen]
+	}
+
+	return result
+}
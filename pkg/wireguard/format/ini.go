@@ -0,0 +1,27 @@
+package format
+
+// INI renders configurations in the wg-quick INI format.
+type INI struct{}
+
+// NewINI initializes and returns an INI formatter.
+func NewINI() *INI {
+	return &INI{}
+}
+
+func (f *INI) Name() string { return "ini" }
+
+func (f *INI) Extension() string { return ".conf" }
+
+// Format renders the configuration with Configuration.ToINIFormat.
+func (f *INI) Format(item Item) ([]byte, error) {
+	ini, err := item.Configuration.ToINIFormat()
+	if err != nil {
+		return nil, err
+	}
+	return []byte(ini), nil
+}
+
+// FormatBatch writes one .conf file per configuration.
+func (f *INI) FormatBatch(items []Item) ([]File, error) {
+	return Each(f, items, 0)
+}
@@ -0,0 +1,28 @@
+package format
+
+// IPC renders configurations as WireGuard UAPI set operations, ready to be
+// piped into a userspace implementation's control socket.
+type IPC struct{}
+
+// NewIPC initializes and returns an IPC formatter.
+func NewIPC() *IPC {
+	return &IPC{}
+}
+
+func (f *IPC) Name() string { return "ipc" }
+
+func (f *IPC) Extension() string { return ".ipc" }
+
+// Format renders the configuration with Configuration.ToIPCFormat.
+func (f *IPC) Format(item Item) ([]byte, error) {
+	ipc, err := item.Configuration.ToIPCFormat()
+	if err != nil {
+		return nil, err
+	}
+	return []byte(ipc), nil
+}
+
+// FormatBatch writes one .ipc file per configuration.
+func (f *IPC) FormatBatch(items []Item) ([]File, error) {
+	return Each(f, items, 0)
+}
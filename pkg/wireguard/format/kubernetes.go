@@ -0,0 +1,311 @@
+package format
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/xbnz/wireguard-config-generator/pkg/wireguard"
+)
+
+const (
+	// DefaultKubernetesNameTemplate names Secrets after their item.
+	DefaultKubernetesNameTemplate = "wireguard-{{.Name}}"
+
+	kubernetesLabelPrefix       = "wireguard-config-generator/"
+	kubernetesSecretKey         = "wg0.conf"
+	kubernetesNameMaxLen        = 253
+	kubernetesLabelMaxLen       = 63
+	kubernetesBundleName        = "secrets.yaml"
+	kubernetesKustomizationName = "kustomization.yaml"
+)
+
+// KubernetesOptions tunes the Kubernetes output.
+type KubernetesOptions struct {
+	// Single writes all Secrets into one multi-document secrets.yaml.
+	Single bool
+	// NameTemplate is a text/template executed with the Item to name each
+	// Secret. The result is turned into a valid resource name.
+	NameTemplate string
+	// Namespace is set on every Secret when not empty.
+	Namespace string
+	// Kustomization adds a kustomization.yaml listing the manifests.
+	Kustomization bool
+}
+
+// Kubernetes renders configurations as Secret manifests holding the
+// wg-quick INI under wg0.conf, labelled and annotated with server metadata.
+type Kubernetes struct {
+	options KubernetesOptions
+}
+
+// NewKubernetes initializes and returns a Kubernetes formatter.
+func NewKubernetes(options KubernetesOptions) *Kubernetes {
+	if options.NameTemplate == "" {
+		options.NameTemplate = DefaultKubernetesNameTemplate
+	}
+	return &Kubernetes{options: options}
+}
+
+func (f *Kubernetes) Name() string { return "kubernetes" }
+
+// Extension marks the manifests as Secrets, keeping them apart from other
+// YAML written into the same directory.
+func (f *Kubernetes) Extension() string { return ".secret.yaml" }
+
+type kubernetesMetadata struct {
+	Name        string            `yaml:"name"`
+	Namespace   string            `yaml:"namespace,omitempty"`
+	Labels      map[string]string `yaml:"labels,omitempty"`
+	Annotations map[string]string `yaml:"annotations,omitempty"`
+}
+
+type kubernetesSecret struct {
+	APIVersion string             `yaml:"apiVersion"`
+	Kind       string             `yaml:"kind"`
+	Metadata   kubernetesMetadata `yaml:"metadata"`
+	Type       string             `yaml:"type"`
+	StringData map[string]string  `yaml:"stringData"`
+}
+
+type kubernetesKustomization struct {
+	APIVersion string   `yaml:"apiVersion"`
+	Kind       string   `yaml:"kind"`
+	Resources  []string `yaml:"resources"`
+}
+
+// Format renders the Secret manifest of a single configuration.
+func (f *Kubernetes) Format(item Item) ([]byte, error) {
+	nameTemplate, err := f.parseNameTemplate()
+	if err != nil {
+		return nil, err
+	}
+	return f.render(nameTemplate, item)
+}
+
+// FormatBatch writes a manifest per configuration, or a single
+// multi-document secrets.yaml, plus the optional kustomization.yaml. The
+// name template is parsed once for all of them.
+func (f *Kubernetes) FormatBatch(items []Item) ([]File, error) {
+	nameTemplate, err := f.parseNameTemplate()
+	if err != nil {
+		return nil, err
+	}
+
+	var files []File
+
+	for _, item := range items {
+		content, err := f.render(nameTemplate, item)
+		if err != nil {
+			return nil, fmt.Errorf(
+				"format %s as %s: %w",
+				item.Name,
+				f.Name(),
+				err,
+			)
+		}
+
+		files = append(files, File{
+			Name:    item.Name + f.Extension(),
+			Content: content,
+		})
+	}
+
+	if f.options.Single {
+		documents := make([][]byte, 0, len(files))
+		for _, file := range files {
+			documents = append(documents, file.Content)
+		}
+
+		files = []File{{
+			Name:    kubernetesBundleName,
+			Content: bytes.Join(documents, []byte("---\n")),
+		}}
+	}
+
+	if !f.options.Kustomization {
+		return files, nil
+	}
+
+	kustomization := kubernetesKustomization{
+		APIVersion: "kustomize.config.k8s.io/v1beta1",
+		Kind:       "Kustomization",
+	}
+	for _, file := range files {
+		kustomization.Resources = append(kustomization.Resources, file.Name)
+	}
+
+	content, err := wireguard.MarshalYAML(kustomization)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(
+		files,
+		File{
+			Name:    kubernetesKustomizationName,
+			Content: content,
+			Mode:    publicFileMode,
+		},
+	), nil
+}
+
+func (f *Kubernetes) parseNameTemplate() (*template.Template, error) {
+	nameTemplate, err := template.New("name").Parse(f.options.NameTemplate)
+	if err != nil {
+		return nil, fmt.Errorf("parse name template: %w", err)
+	}
+	return nameTemplate, nil
+}
+
+func (f *Kubernetes) render(
+	nameTemplate *template.Template,
+	item Item,
+) ([]byte, error) {
+	secret, err := f.secret(nameTemplate, item)
+	if err != nil {
+		return nil, err
+	}
+
+	return wireguard.MarshalYAML(secret)
+}
+
+func (f *Kubernetes) secret(
+	nameTemplate *template.Template,
+	item Item,
+) (kubernetesSecret, error) {
+	ini, err := item.Configuration.ToINIFormat()
+	if err != nil {
+		return kubernetesSecret{}, err
+	}
+
+	name, err := f.secretName(nameTemplate, item)
+	if err != nil {
+		return kubernetesSecret{}, err
+	}
+
+	return kubernetesSecret{
+		APIVersion: "v1",
+		Kind:       "Secret",
+		Metadata: kubernetesMetadata{
+			Name:        name,
+			Namespace:   f.options.Namespace,
+			Labels:      kubernetesLabels(item),
+			Annotations: kubernetesAnnotations(item),
+		},
+		Type:       "Opaque",
+		StringData: map[string]string{kubernetesSecretKey: ini},
+	}, nil
+}
+
+func (f *Kubernetes) secretName(
+	nameTemplate *template.Template,
+	item Item,
+) (string, error) {
+	var sb strings.Builder
+	if err := nameTemplate.Execute(&sb, item); err != nil {
+		return "", fmt.Errorf("execute name template: %w", err)
+	}
+
+	name := kubernetesName(sb.String())
+	if name == "" {
+		return "", fmt.Errorf(
+			"name template %q renders an empty name",
+			f.options.NameTemplate,
+		)
+	}
+
+	return name, nil
+}
+
+func kubernetesLabels(item Item) map[string]string {
+	labels := map[string]string{
+		"app.kubernetes.io/name":       "wireguard",
+		"app.kubernetes.io/managed-by": "wireguard-config-generator",
+	}
+
+	metadata := item.Server.Metadata
+	for key, value := range map[string]string{
+		"country-code": strings.ToLower(metadata.CountryCode),
+		"country":      metadata.Country,
+		"city":         metadata.City,
+	} {
+		if value := kubernetesLabelValue(value); value != "" {
+			labels[kubernetesLabelPrefix+key] = value
+		}
+	}
+
+	return labels
+}
+
+func kubernetesAnnotations(item Item) map[string]string {
+	annotations := map[string]string{}
+
+	metadata := item.Server.Metadata
+	values := map[string]string{
+		"hostname": metadata.Hostname,
+		"country":  metadata.Country,
+		"city":     metadata.City,
+	}
+	if metadata.Load > 0 {
+		values["load"] = strconv.Itoa(metadata.Load)
+	}
+	if len(item.Configuration.Peers) > 0 {
+		peer := item.Configuration.Peers[0]
+		values["public-key"] = peer.PublicKey
+		values["endpoint"] = peer.EndpointString()
+	}
+
+	for key, value := range values {
+		if value != "" {
+			annotations[kubernetesLabelPrefix+key] = value
+		}
+	}
+
+	return annotations
+}
+
+// kubernetesName turns name into a resource name: lowercase letters, digits,
+// dashes and dots, starting and ending with a letter or digit.
+func kubernetesName(name string) string {
+	var sb strings.Builder
+	for _, r := range strings.ToLower(name) {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9', r == '-', r == '.':
+			sb.WriteRune(r)
+		default:
+			sb.WriteRune('-')
+		}
+	}
+
+	result := sb.String()
+	if len(result) > kubernetesNameMaxLen {
+		result = result[:kubernetesNameMaxLen]
+	}
+
+	return strings.Trim(result, "-.")
+}
+
+// kubernetesLabelValue turns value into a valid label value, keeping its
+// case.
+func kubernetesLabelValue(value string) string {
+	var sb strings.Builder
+	for _, r := range value {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9',
+			r == '-', r == '_', r == '.':
+			sb.WriteRune(r)
+		default:
+			sb.WriteRune('_')
+		}
+	}
+
+	result := sb.String()
+	if len(result) > kubernetesLabelMaxLen {
+		result = result[:kubernetesLabelMaxLen]
+	}
+
+	return strings.Trim(result, "-_.")
+}
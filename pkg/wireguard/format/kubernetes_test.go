@@ -0,0 +1,83 @@
+package format
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
+)
+
+func TestKubernetes(t *testing.T) {
+	t.Parallel()
+
+	t.Run("single manifests are listed by the kustomization", func(t *testing.T) {
+		t.Parallel()
+
+		files, err := NewKubernetes(KubernetesOptions{
+			Single:        true,
+			NameTemplate:  "vpn-{{.Server.Metadata.City}}-{{.Name}}",
+			Namespace:     "vpn",
+			Kustomization: true,
+		}).FormatBatch(testItems())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		assert.Len(t, files, 2)
+		assert.Equal(t, "secrets.yaml", files[0].Name)
+		assert.Equal(t, "kustomization.yaml", files[1].Name)
+		assert.Equal(
+			t,
+			"apiVersion: kustomize.config.k8s.io/v1beta1\n"+
+				"kind: Kustomization\n"+
+				"resources:\n"+
+				"  - secrets.yaml\n",
+			string(files[1].Content),
+		)
+
+		decoder := yaml.NewDecoder(bytes.NewReader(files[0].Content))
+
+		var names []string
+		for {
+			var secret kubernetesSecret
+			if err := decoder.Decode(&secret); err != nil {
+				break
+			}
+			names = append(names, secret.Metadata.Name)
+			assert.Equal(t, "vpn", secret.Metadata.Namespace)
+			assert.Contains(t, secret.StringData["wg0.conf"], "[Interface]\n")
+		}
+
+		assert.Equal(
+			t,
+			[]string{"vpn-frankfurt-nordvpn-0", "vpn-gothenburg-mullvad-1"},
+			names,
+		)
+	})
+
+	t.Run("templates that fail are reported", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := NewKubernetes(KubernetesOptions{NameTemplate: "{{.Nope}}"}).
+			Format(testItems()[0])
+		assert.ErrorContains(t, err, "execute name template")
+	})
+
+	t.Run("malformed templates fail before any item", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := NewKubernetes(KubernetesOptions{NameTemplate: "{{.Name"}).
+			FormatBatch(testItems())
+		assert.ErrorContains(t, err, "parse name template")
+		assert.NotContains(t, err.Error(), "nordvpn_0")
+	})
+
+	t.Run("label values are sanitized", func(t *testing.T) {
+		t.Parallel()
+
+		assert.Equal(t, "New_York", kubernetesLabelValue("New York"))
+		assert.Equal(t, "S_o_Paulo", kubernetesLabelValue("São Paulo"))
+		assert.Equal(t, "a.b", kubernetesLabelValue("(a.b)"))
+	})
+}
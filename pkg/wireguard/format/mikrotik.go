@@ -0,0 +1,208 @@
+package format
+
+import (
+	"fmt"
+	"strings"
+)
+
+// RouterOS backs WireGuard interfaces with Linux network devices, so names are
+// kept within the kernel's limit.
+const mikroTikNameMaxLen = linuxInterfaceNameMaxLen
+
+// MikroTikOptions tunes the RouterOS output.
+type MikroTikOptions struct {
+	// RoutingTable adds a routing table per tunnel holding routes for the
+	// peers' AllowedIPs through it.
+	RoutingTable bool
+	// Mangle adds a mangle rule that marks connections from the address
+	// list named after the tunnel for its routing table. It implies
+	// RoutingTable.
+	Mangle bool
+}
+
+// MikroTik renders configurations as RouterOS v7 scripts that can be run
+// with /import.
+type MikroTik struct {
+	options MikroTikOptions
+}
+
+// NewMikroTik initializes and returns a MikroTik formatter.
+func NewMikroTik(options MikroTikOptions) *MikroTik {
+	if options.Mangle {
+		options.RoutingTable = true
+	}
+	return &MikroTik{options: options}
+}
+
+func (f *MikroTik) Name() string { return "mikrotik" }
+
+func (f *MikroTik) Extension() string { return ".rsc" }
+
+// Format renders the script of a single configuration. The interface,
+// routing table and address list all share one name derived from the item.
+func (f *MikroTik) Format(item Item) ([]byte, error) {
+	if err := checkEndpointAddrs(item.Configuration); err != nil {
+		return nil, err
+	}
+
+	config := item.Configuration
+	name := interfaceName("wg-"+item.Name, mikroTikNameMaxLen)
+	comment := routerOSQuote(item.Name)
+	var sb strings.Builder
+
+	fmt.Fprintf(&sb, "/interface wireguard\n")
+	fmt.Fprintf(
+		&sb,
+		"add name=%s private-key=%s",
+		name,
+		routerOSQuote(config.PrivateKey),
+	)
+	if config.ListenPort > 0 {
+		fmt.Fprintf(&sb, " listen-port=%d", config.ListenPort)
+	}
+	if config.MTU > 0 {
+		fmt.Fprintf(&sb, " mtu=%d", config.MTU)
+	}
+	fmt.Fprintf(&sb, " comment=%s\n", comment)
+
+	fmt.Fprintf(&sb, "/interface wireguard peers\n")
+	for _, peer := range config.Peers {
+		fmt.Fprintf(
+			&sb,
+			"add interface=%s public-key=%s",
+			name,
+			routerOSQuote(peer.PublicKey),
+		)
+		if peer.PresharedKey != "" {
+			fmt.Fprintf(
+				&sb,
+				" preshared-key=%s",
+				routerOSQuote(peer.PresharedKey),
+			)
+		}
+		if peer.Endpoint.IsValid() {
+			fmt.Fprintf(
+				&sb,
+				" endpoint-address=%s endpoint-port=%d",
+				peer.Endpoint.Addr(),
+				peer.Endpoint.Port(),
+			)
+		}
+		fmt.Fprintf(
+			&sb,
+			" allowed-address=%s",
+			joinStrings(peer.AllowedIPs, ","),
+		)
+		if peer.PersistentKeepalive > 0 {
+			fmt.Fprintf(
+				&sb,
+				" persistent-keepalive=%ds",
+				peer.PersistentKeepalive,
+			)
+		}
+		fmt.Fprintf(&sb, " comment=%s\n", comment)
+	}
+
+	addressesV4, addressesV6 := splitPrefixes(config.InterfaceAddresses)
+	if len(addressesV4) > 0 {
+		fmt.Fprintf(&sb, "/ip address\n")
+		for _, address := range addressesV4 {
+			fmt.Fprintf(
+				&sb,
+				"add address=%s interface=%s comment=%s\n",
+				address,
+				name,
+				comment,
+			)
+		}
+	}
+	if len(addressesV6) > 0 {
+		fmt.Fprintf(&sb, "/ipv6 address\n")
+		for _, address := range addressesV6 {
+			fmt.Fprintf(
+				&sb,
+				"add address=%s advertise=no interface=%s comment=%s\n",
+				address,
+				name,
+				comment,
+			)
+		}
+	}
+
+	if f.options.RoutingTable {
+		f.writeRoutes(&sb, item, name, comment)
+	}
+
+	return []byte(sb.String()), nil
+}
+
+// FormatBatch writes one .rsc script per configuration.
+func (f *MikroTik) FormatBatch(items []Item) ([]File, error) {
+	return Each(f, items, 0)
+}
+
+func (f *MikroTik) writeRoutes(
+	sb *strings.Builder,
+	item Item,
+	name string,
+	comment string,
+) {
+	var routesV4, routesV6 []string
+	for _, peer := range item.Configuration.Peers {
+		v4, v6 := splitPrefixes(peer.AllowedIPs)
+		for _, route := range v4 {
+			routesV4 = append(routesV4, route.String())
+		}
+		for _, route := range v6 {
+			routesV6 = append(routesV6, route.String())
+		}
+	}
+
+	fmt.Fprintf(sb, "/routing table\n")
+	fmt.Fprintf(sb, "add name=%s fib comment=%s\n", name, comment)
+
+	for _, family := range []struct {
+		menu   string
+		routes []string
+	}{
+		{"/ip route", routesV4},
+		{"/ipv6 route", routesV6},
+	} {
+		if len(family.routes) == 0 {
+			continue
+		}
+
+		fmt.Fprintf(sb, "%s\n", family.menu)
+		for _, route := range family.routes {
+			fmt.Fprintf(
+				sb,
+				"add dst-address=%s gateway=%s routing-table=%s comment=%s\n",
+				route,
+				name,
+				name,
+				comment,
+			)
+		}
+	}
+
+	if !f.options.Mangle {
+		return
+	}
+
+	fmt.Fprintf(sb, "/ip firewall mangle\n")
+	fmt.Fprintf(
+		sb,
+		"add chain=prerouting src-address-list=%s action=mark-routing "+
+			"new-routing-mark=%s passthrough=no comment=%s\n",
+		name,
+		name,
+		comment,
+	)
+}
+
+// routerOSQuote double quotes a value and escapes the characters RouterOS
+// treats specially inside quotes.
+func routerOSQuote(value string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, `"`, `\"`, `$`, `\$`)
+	return `"` + replacer.Replace(value) + `"`
+}
@@ -0,0 +1,47 @@
+package format
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMikroTik(t *testing.T) {
+	t.Parallel()
+
+	t.Run("mangle rules come with a routing table", func(t *testing.T) {
+		t.Parallel()
+
+		files, err := NewMikroTik(MikroTikOptions{Mangle: true}).
+			FormatBatch(testItems()[1:])
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		assertGolden(
+			t,
+			"testdata/mikrotik/mangle/mullvad_1.rsc.golden",
+			files[0].Content,
+		)
+	})
+
+	t.Run("names fit the interface name limit", func(t *testing.T) {
+		t.Parallel()
+
+		item := testItems()[0]
+		item.Name = "nordvpn_germany_1234"
+
+		content, err := NewMikroTik(MikroTikOptions{}).Format(item)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		assert.Contains(t, string(content), "add name=wg-nordvpn_germ ")
+	})
+
+	t.Run("quoted values escape special characters", func(t *testing.T) {
+		t.Parallel()
+
+		assert.Equal(t, `"a\"b\$c\\d"`, routerOSQuote(`a"b$c\d`))
+	})
+}
@@ -0,0 +1,178 @@
+package format
+
+import (
+	"github.com/xbnz/wireguard-config-generator/pkg/wireguard"
+)
+
+// Netplan refuses to apply configurations readable by other users.
+const netplanFileMode = 0o600
+
+// NetplanOptions tunes the Netplan output.
+type NetplanOptions struct {
+	// RouteTable puts the routes for the peers' AllowedIPs into this routing
+	// table, ready to be used by routing-policy rules. Zero keeps them in the
+	// main table and leaves default routes out, as those would also carry
+	// the encrypted traffic to the endpoint into the tunnel.
+	RouteTable uint32
+	// Warnf reports how many configurations had their default routes left
+	// out. It has the signature of log.Printf.
+	Warnf func(format string, v ...any)
+}
+
+// Netplan renders configurations as Netplan YAML declaring a wireguard
+// tunnel, ready to be dropped into /etc/netplan.
+type Netplan struct {
+	options NetplanOptions
+}
+
+// NewNetplan initializes and returns a Netplan formatter.
+func NewNetplan(options NetplanOptions) *Netplan {
+	if options.Warnf == nil {
+		options.Warnf = func(string, ...any) {}
+	}
+	return &Netplan{options: options}
+}
+
+func (f *Netplan) Name() string { return "netplan" }
+
+// Extension keeps the files apart from the yaml format while still ending
+// in .yaml, which is all Netplan looks for.
+func (f *Netplan) Extension() string { return ".netplan.yaml" }
+
+type netplanDocument struct {
+	Network netplanNetwork `yaml:"network"`
+}
+
+type netplanNetwork struct {
+	Version int                      `yaml:"version"`
+	Tunnels map[string]netplanTunnel `yaml:"tunnels"`
+}
+
+type netplanTunnel struct {
+	Mode        string              `yaml:"mode"`
+	Key         string              `yaml:"key"`
+	Port        uint16              `yaml:"port,omitempty"`
+	Mark        uint32              `yaml:"mark,omitempty"`
+	MTU         uint16              `yaml:"mtu,omitempty"`
+	Addresses   []string            `yaml:"addresses"`
+	Nameservers *netplanNameservers `yaml:"nameservers,omitempty"`
+	Routes      []netplanRoute      `yaml:"routes,omitempty"`
+	Peers       []netplanPeer       `yaml:"peers"`
+}
+
+type netplanNameservers struct {
+	Addresses []string `yaml:"addresses,omitempty"`
+	Search    []string `yaml:"search,omitempty"`
+}
+
+type netplanRoute struct {
+	To    string `yaml:"to"`
+	Scope string `yaml:"scope"`
+	Table uint32 `yaml:"table,omitempty"`
+}
+
+type netplanPeer struct {
+	Keys       netplanPeerKeys `yaml:"keys"`
+	AllowedIPs []string        `yaml:"allowed-ips"`
+	Endpoint   string          `yaml:"endpoint,omitempty"`
+	Keepalive  uint16          `yaml:"keepalive,omitempty"`
+}
+
+type netplanPeerKeys struct {
+	Public string `yaml:"public"`
+	Shared string `yaml:"shared,omitempty"`
+}
+
+// Format renders the Netplan file of a single configuration. Routes come
+// from the peers' AllowedIPs, like in the networkd output Netplan renders
+// to. Default routes are only added with a route table.
+func (f *Netplan) Format(item Item) ([]byte, error) {
+	if err := checkEndpointAddrs(item.Configuration); err != nil {
+		return nil, err
+	}
+
+	config := item.Configuration
+
+	tunnel := netplanTunnel{
+		Mode:      "wireguard",
+		Key:       config.PrivateKey,
+		Port:      config.ListenPort,
+		Mark:      config.FwMark,
+		MTU:       config.MTU,
+		Addresses: toStrings(config.InterfaceAddresses),
+	}
+
+	if len(config.DNS) > 0 || len(config.DNSSearch) > 0 {
+		tunnel.Nameservers = &netplanNameservers{
+			Addresses: toStrings(config.DNS),
+			Search:    config.DNSSearch,
+		}
+	}
+
+	for _, peer := range config.Peers {
+		for _, route := range peer.AllowedIPs {
+			if f.options.RouteTable == 0 && route.Bits() == 0 {
+				continue
+			}
+
+			tunnel.Routes = append(tunnel.Routes, netplanRoute{
+				To:    route.String(),
+				Scope: "link",
+				Table: f.options.RouteTable,
+			})
+		}
+
+		netplanPeer := netplanPeer{
+			Keys: netplanPeerKeys{
+				Public: peer.PublicKey,
+				Shared: peer.PresharedKey,
+			},
+			AllowedIPs: toStrings(peer.AllowedIPs),
+			Keepalive:  peer.PersistentKeepalive,
+		}
+		if peer.Endpoint.IsValid() {
+			netplanPeer.Endpoint = peer.Endpoint.String()
+		}
+
+		tunnel.Peers = append(tunnel.Peers, netplanPeer)
+	}
+
+	return wireguard.MarshalYAML(netplanDocument{
+		Network: netplanNetwork{
+			Version: 2,
+			Tunnels: map[string]netplanTunnel{
+				interfaceName(item.Name, linuxInterfaceNameMaxLen): tunnel,
+			},
+		},
+	})
+}
+
+// FormatBatch writes one Netplan file per configuration. Without a route
+// table, full tunnels lose their default routes, which is reported once
+// through Warnf.
+func (f *Netplan) FormatBatch(items []Item) ([]File, error) {
+	files, err := Each(f, items, netplanFileMode)
+	if err != nil {
+		return nil, err
+	}
+
+	if f.options.RouteTable == 0 {
+		fullTunnels := 0
+		for _, item := range items {
+			if item.Configuration.IsFullTunnel() {
+				fullTunnels++
+			}
+		}
+
+		if fullTunnels > 0 {
+			f.options.Warnf(
+				"netplan: left out the default routes of %d of %d "+
+					"configurations, they need a route table",
+				fullTunnels,
+				len(items),
+			)
+		}
+	}
+
+	return files, nil
+}
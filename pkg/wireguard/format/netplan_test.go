@@ -0,0 +1,112 @@
+package format
+
+import (
+	"fmt"
+	"io/fs"
+	"net/netip"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNetplan(t *testing.T) {
+	t.Parallel()
+
+	t.Run("files are private to their owner", func(t *testing.T) {
+		t.Parallel()
+
+		files, err := NewNetplan(NetplanOptions{}).FormatBatch(testItems())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, file := range files {
+			assert.Equal(t, fs.FileMode(0o600), file.Mode)
+		}
+	})
+
+	t.Run("search domains join the nameservers", func(t *testing.T) {
+		t.Parallel()
+
+		item := testItems()[0]
+		item.Name = "corp vpn"
+		item.Configuration.DNSSearch = []string{"corp.example"}
+
+		content, err := NewNetplan(NetplanOptions{}).Format(item)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		assert.Contains(t, string(content), "    corp_vpn:\n")
+		assert.Contains(
+			t,
+			string(content),
+			"      nameservers:\n"+
+				"        addresses:\n"+
+				"          - 103.86.96.100\n"+
+				"        search:\n"+
+				"          - corp.example\n",
+		)
+	})
+	t.Run("default routes are left out without a table", func(t *testing.T) {
+		t.Parallel()
+
+		item := testItems()[0]
+		item.Configuration.Peers[0].AllowedIPs = []netip.Prefix{
+			netip.MustParsePrefix("0.0.0.0/0"),
+			netip.MustParsePrefix("10.0.0.0/8"),
+		}
+
+		content, err := NewNetplan(NetplanOptions{}).Format(item)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		assert.Contains(
+			t,
+			string(content),
+			"      routes:\n"+
+				"        - to: 10.0.0.0/8\n"+
+				"          scope: link\n"+
+				"      peers:\n",
+		)
+	})
+
+	t.Run("left out default routes are reported once", func(t *testing.T) {
+		t.Parallel()
+
+		var warnings []string
+		_, err := NewNetplan(NetplanOptions{
+			Warnf: func(format string, v ...any) {
+				warnings = append(warnings, fmt.Sprintf(format, v...))
+			},
+		}).FormatBatch(testItems())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		assert.Equal(t, []string{
+			"netplan: left out the default routes of 2 of 2 configurations, " +
+				"they need a route table",
+		}, warnings)
+	})
+
+	t.Run("a route table takes the default routes", func(t *testing.T) {
+		t.Parallel()
+
+		content, err := NewNetplan(NetplanOptions{RouteTable: 100}).
+			Format(testItems()[0])
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		assert.Contains(
+			t,
+			string(content),
+			"      routes:\n"+
+				"        - to: 0.0.0.0/0\n"+
+				"          scope: link\n"+
+				"          table: 100\n",
+		)
+	})
+}
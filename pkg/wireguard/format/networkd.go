@@ -0,0 +1,290 @@
+package format
+
+import (
+	"fmt"
+	"io/fs"
+	"net/netip"
+	"path"
+	"strings"
+
+	"github.com/xbnz/wireguard-config-generator/pkg/wireguard"
+)
+
+const (
+	// systemd-networkd reads secrets as the systemd-network user, so files
+	// holding keys are meant to be owned by root:systemd-network.
+	networkdSecretFileMode fs.FileMode = 0o640
+
+	// DefaultNetworkdKeyDir is where PrivateKeyFile points at by default.
+	DefaultNetworkdKeyDir = "/etc/systemd/network"
+
+	// DefaultNetworkdRouteTable is the routing table of full tunnels when
+	// no RouteTable is set, the one wg-quick picks for Table = auto.
+	DefaultNetworkdRouteTable = 51820
+
+	networkdPolicyRulePriority = 10
+)
+
+// NetworkdOptions tunes the systemd-networkd output.
+type NetworkdOptions struct {
+	// KeyFile writes the private key to a separate <name>.key credential
+	// file referenced by PrivateKeyFile instead of inlining it in the
+	// .netdev file. Preshared keys stay inline, so a .netdev with one is
+	// written with the same mode as the key file.
+	KeyFile bool
+	// KeyDir is the directory PrivateKeyFile points at once the files are
+	// installed.
+	KeyDir string
+	// RouteTable puts the routes derived from AllowedIPs into this routing
+	// table and adds fwmark policy rules that send everything but the
+	// tunnel's own traffic to it, like wg-quick does. Zero keeps the routes
+	// of split tunnels in the main table and puts full tunnels into
+	// DefaultNetworkdRouteTable, since a default route in the main table
+	// would send the encrypted traffic to the endpoint into the tunnel too.
+	RouteTable uint32
+}
+
+// Networkd renders configurations as a systemd-networkd .netdev and
+// .network pair.
+type Networkd struct {
+	options NetworkdOptions
+}
+
+// NewNetworkd initializes and returns a Networkd formatter.
+func NewNetworkd(options NetworkdOptions) *Networkd {
+	if options.KeyDir == "" {
+		options.KeyDir = DefaultNetworkdKeyDir
+	}
+	return &Networkd{options: options}
+}
+
+func (f *Networkd) Name() string { return "networkd" }
+
+func (f *Networkd) Extension() string { return ".netdev" }
+
+// Format renders the .netdev file of a configuration.
+func (f *Networkd) Format(item Item) ([]byte, error) {
+	config := item.Configuration
+	var sb strings.Builder
+
+	fmt.Fprintf(&sb, "[NetDev]\n")
+	fmt.Fprintf(
+		&sb,
+		"Name=%s\n",
+		interfaceName(item.Name, linuxInterfaceNameMaxLen),
+	)
+	fmt.Fprintf(&sb, "Kind=wireguard\n")
+	fmt.Fprintf(&sb, "Description=WireGuard tunnel %s\n", item.Name)
+	if config.MTU > 0 {
+		fmt.Fprintf(&sb, "MTUBytes=%d\n", config.MTU)
+	}
+
+	fmt.Fprintf(&sb, "\n[WireGuard]\n")
+	if f.options.KeyFile {
+		fmt.Fprintf(
+			&sb,
+			"PrivateKeyFile=%s\n",
+			path.Join(f.options.KeyDir, f.keyFileName(item)),
+		)
+	} else {
+		fmt.Fprintf(&sb, "PrivateKey=%s\n", config.PrivateKey)
+	}
+	if config.ListenPort > 0 {
+		fmt.Fprintf(&sb, "ListenPort=%d\n", config.ListenPort)
+	}
+	if mark := f.firewallMark(item); mark > 0 {
+		fmt.Fprintf(&sb, "FirewallMark=%d\n", mark)
+	}
+
+	for _, peer := range config.Peers {
+		fmt.Fprintf(&sb, "\n[WireGuardPeer]\n")
+		fmt.Fprintf(&sb, "PublicKey=%s\n", peer.PublicKey)
+		if peer.PresharedKey != "" {
+			fmt.Fprintf(&sb, "PresharedKey=%s\n", peer.PresharedKey)
+		}
+		fmt.Fprintf(&sb, "AllowedIPs=%s\n", joinStrings(peer.AllowedIPs, ","))
+		if endpoint := peer.EndpointString(); endpoint != "" {
+			fmt.Fprintf(&sb, "Endpoint=%s\n", endpoint)
+		}
+		if peer.PersistentKeepalive > 0 {
+			fmt.Fprintf(
+				&sb,
+				"PersistentKeepalive=%d\n",
+				peer.PersistentKeepalive,
+			)
+		}
+	}
+
+	return []byte(sb.String()), nil
+}
+
+// hasPresharedKey reports whether any peer of the configuration has a
+// preshared key.
+func hasPresharedKey(config wireguard.Configuration) bool {
+	for _, peer := range config.Peers {
+		if peer.PresharedKey != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// FormatBatch writes a .netdev and a .network file per configuration, plus
+// a .key credential file when KeyFile is set.
+func (f *Networkd) FormatBatch(items []Item) ([]File, error) {
+	var files []File
+
+	for _, item := range items {
+		netdev, err := f.Format(item)
+		if err != nil {
+			return nil, fmt.Errorf(
+				"format %s as %s: %w",
+				item.Name,
+				f.Name(),
+				err,
+			)
+		}
+
+		netdevMode := networkdSecretFileMode
+		if f.options.KeyFile && !hasPresharedKey(item.Configuration) {
+			netdevMode = publicFileMode
+		}
+
+		files = append(
+			files,
+			File{
+				Name:    item.Name + ".netdev",
+				Content: netdev,
+				Mode:    netdevMode,
+			},
+			File{
+				Name:    item.Name + ".network",
+				Content: f.network(item),
+				Mode:    publicFileMode,
+			},
+		)
+
+		if f.options.KeyFile {
+			files = append(files, File{
+				Name:    f.keyFileName(item),
+				Content: []byte(item.Configuration.PrivateKey + "\n"),
+				Mode:    networkdSecretFileMode,
+			})
+		}
+	}
+
+	return files, nil
+}
+
+// network renders the .network file of a configuration.
+func (f *Networkd) network(item Item) []byte {
+	config := item.Configuration
+	var sb strings.Builder
+
+	allowedIPs := networkdAllowedIPs(config.Peers)
+	routesV4, routesV6 := splitPrefixes(allowedIPs)
+	table := f.routeTable(item)
+
+	fmt.Fprintf(&sb, "[Match]\n")
+	fmt.Fprintf(
+		&sb,
+		"Name=%s\n",
+		interfaceName(item.Name, linuxInterfaceNameMaxLen),
+	)
+
+	fmt.Fprintf(&sb, "\n[Network]\n")
+	for _, address := range config.InterfaceAddresses {
+		fmt.Fprintf(&sb, "Address=%s\n", address)
+	}
+	for _, dns := range config.DNS {
+		fmt.Fprintf(&sb, "DNS=%s\n", dns)
+	}
+
+	domains := config.DNSSearch
+	if len(config.DNS) > 0 && config.IsFullTunnel() {
+		// Route every DNS query to the tunnel's servers so none leak out
+		// through other links.
+		domains = append(domains[:len(domains):len(domains)], "~.")
+	}
+	if len(domains) > 0 {
+		fmt.Fprintf(&sb, "Domains=%s\n", strings.Join(domains, " "))
+	}
+
+	for _, route := range allowedIPs {
+		fmt.Fprintf(&sb, "\n[Route]\n")
+		fmt.Fprintf(&sb, "Destination=%s\n", route)
+		if table > 0 {
+			fmt.Fprintf(&sb, "Table=%d\n", table)
+		}
+	}
+
+	if table > 0 {
+		mark := f.firewallMark(item)
+
+		for _, family := range []struct {
+			name   string
+			routes []netip.Prefix
+		}{
+			{"ipv4", routesV4},
+			{"ipv6", routesV6},
+		} {
+			if len(family.routes) == 0 {
+				continue
+			}
+
+			fmt.Fprintf(&sb, "\n[RoutingPolicyRule]\n")
+			fmt.Fprintf(&sb, "Family=%s\n", family.name)
+			fmt.Fprintf(&sb, "FirewallMark=%d\n", mark)
+			fmt.Fprintf(&sb, "InvertRule=yes\n")
+			fmt.Fprintf(&sb, "Table=%d\n", table)
+			fmt.Fprintf(&sb, "Priority=%d\n", networkdPolicyRulePriority)
+
+			if hasDefaultRoute(family.routes) {
+				// Keep more specific routes of the main table, such as
+				// the LAN, reachable while the default route is
+				// replaced.
+				fmt.Fprintf(&sb, "\n[RoutingPolicyRule]\n")
+				fmt.Fprintf(&sb, "Family=%s\n", family.name)
+				fmt.Fprintf(&sb, "Table=main\n")
+				fmt.Fprintf(&sb, "SuppressPrefixLength=0\n")
+				fmt.Fprintf(&sb, "Priority=%d\n", networkdPolicyRulePriority-1)
+			}
+		}
+	}
+
+	return []byte(sb.String())
+}
+
+func (f *Networkd) keyFileName(item Item) string {
+	return item.Name + ".key"
+}
+
+// firewallMark returns the fwmark the tunnel's own packets carry. With a
+// route table and no explicit FwMark the table number doubles as the mark,
+// following wg-quick.
+func (f *Networkd) firewallMark(item Item) uint32 {
+	if item.Configuration.FwMark > 0 {
+		return item.Configuration.FwMark
+	}
+	return f.routeTable(item)
+}
+
+// routeTable returns the table the routes of a configuration go into, or
+// zero for the main table.
+func (f *Networkd) routeTable(item Item) uint32 {
+	if f.options.RouteTable > 0 {
+		return f.options.RouteTable
+	}
+	if hasDefaultRoute(networkdAllowedIPs(item.Configuration.Peers)) {
+		return DefaultNetworkdRouteTable
+	}
+	return 0
+}
+
+func networkdAllowedIPs(peers []wireguard.PeerConfig) []netip.Prefix {
+	var allowedIPs []netip.Prefix
+	for _, peer := range peers {
+		allowedIPs = append(allowedIPs, peer.AllowedIPs...)
+	}
+	return allowedIPs
+}
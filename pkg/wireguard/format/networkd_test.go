@@ -0,0 +1,124 @@
+package format
+
+import (
+	"io/fs"
+	"net/netip"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNetworkd(t *testing.T) {
+	t.Parallel()
+
+	t.Run("inline private keys are not world readable", func(t *testing.T) {
+		t.Parallel()
+
+		files, err := NewNetworkd(NetworkdOptions{}).FormatBatch(testItems())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		assert.Len(t, files, 4)
+		assert.Equal(t, "nordvpn_0.netdev", files[0].Name)
+		assert.Equal(t, fs.FileMode(0o640), files[0].Mode)
+		assert.Equal(t, "nordvpn_0.network", files[1].Name)
+		assert.Equal(t, fs.FileMode(0o644), files[1].Mode)
+	})
+
+	t.Run("private keys can be written to credential files", func(t *testing.T) {
+		t.Parallel()
+
+		files, err := NewNetworkd(NetworkdOptions{
+			KeyFile: true,
+			KeyDir:  "/run/credentials",
+		}).FormatBatch(testItems()[:1])
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		assert.Len(t, files, 3)
+		assert.Contains(
+			t,
+			string(files[0].Content),
+			"PrivateKeyFile=/run/credentials/nordvpn_0.key\n",
+		)
+		assert.NotContains(t, string(files[0].Content), "PrivateKey=")
+		assert.Equal(t, fs.FileMode(0o644), files[0].Mode)
+		assert.Equal(t, "nordvpn_0.key", files[2].Name)
+		assert.Equal(
+			t,
+			"OEvHuuMpALNf7ZZkzUSGbT8vkj89aHrhLyqZlIn4rPU=\n",
+			string(files[2].Content),
+		)
+		assert.Equal(t, fs.FileMode(0o640), files[2].Mode)
+	})
+
+	t.Run("netdevs with preshared keys stay private", func(t *testing.T) {
+		t.Parallel()
+
+		files, err := NewNetworkd(NetworkdOptions{
+			KeyFile: true,
+		}).FormatBatch(testItems()[1:])
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		assert.Equal(t, "mullvad_1.netdev", files[0].Name)
+		assert.Contains(t, string(files[0].Content), "PresharedKey=")
+		assert.Equal(t, fs.FileMode(0o640), files[0].Mode)
+	})
+
+	t.Run("route tables add fwmark policy rules", func(t *testing.T) {
+		t.Parallel()
+
+		files, err := NewNetworkd(NetworkdOptions{RouteTable: 51820}).
+			FormatBatch(testItems()[1:])
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		assert.Contains(t, string(files[0].Content), "FirewallMark=51820\n")
+		assert.Contains(
+			t,
+			string(files[1].Content),
+			"[Route]\nDestination=::/0\nTable=51820\n",
+		)
+		assert.Contains(
+			t,
+			string(files[1].Content),
+			"[RoutingPolicyRule]\n"+
+				"Family=ipv6\n"+
+				"FirewallMark=51820\n"+
+				"InvertRule=yes\n"+
+				"Table=51820\n"+
+				"Priority=10\n"+
+				"\n"+
+				"[RoutingPolicyRule]\n"+
+				"Family=ipv6\n"+
+				"Table=main\n"+
+				"SuppressPrefixLength=0\n"+
+				"Priority=9\n",
+		)
+	})
+	t.Run("split tunnels stay in the main table", func(t *testing.T) {
+		t.Parallel()
+
+		item := testItems()[0]
+		item.Configuration.Peers[0].AllowedIPs = []netip.Prefix{
+			netip.MustParsePrefix("10.0.0.0/8"),
+		}
+
+		files, err := NewNetworkd(NetworkdOptions{}).FormatBatch([]Item{item})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		assert.NotContains(t, string(files[0].Content), "FirewallMark=")
+		assert.True(t, strings.HasSuffix(
+			string(files[1].Content),
+			"[Route]\nDestination=10.0.0.0/8\n",
+		))
+	})
+}
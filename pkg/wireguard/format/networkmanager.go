@@ -0,0 +1,216 @@
+package format
+
+import (
+	"fmt"
+	"net/netip"
+	"strings"
+)
+
+const (
+	// NetworkManager refuses to load keyfiles readable by anyone but root.
+	networkManagerFileMode = 0o600
+
+	// A negative priority makes the tunnel's DNS servers the only ones used
+	// while it is up, which is what a full tunnel needs to avoid DNS leaks.
+	networkManagerFullTunnelDNSPriority  = -50
+	networkManagerSplitTunnelDNSPriority = 50
+
+	linuxInterfaceNameMaxLen = 15
+)
+
+// NetworkManagerOptions tunes the NetworkManager keyfile output.
+type NetworkManagerOptions struct {
+	// Warnf reports DNS servers that are left out because the tunnel has no
+	// address of their family. It has the signature of log.Printf.
+	Warnf func(format string, v ...any)
+}
+
+// NetworkManager renders configurations as NetworkManager keyfiles of type
+// wireguard, ready to be dropped into
+// /etc/NetworkManager/system-connections.
+type NetworkManager struct {
+	warnf func(format string, v ...any)
+}
+
+// NewNetworkManager initializes and returns a NetworkManager formatter.
+func NewNetworkManager(options NetworkManagerOptions) *NetworkManager {
+	if options.Warnf == nil {
+		options.Warnf = func(string, ...any) {}
+	}
+
+	return &NetworkManager{warnf: options.Warnf}
+}
+
+func (f *NetworkManager) Name() string { return "networkmanager" }
+
+func (f *NetworkManager) Extension() string { return ".nmconnection" }
+
+// Format renders a single keyfile. Routes come from the peers' AllowedIPs,
+// so never-default is only cleared for address families the tunnel carries
+// a default route for. An address family without addresses is disabled
+// together with its DNS settings, so search domains go into the section of a
+// family that has addresses and dropped DNS servers are reported through
+// Warnf.
+func (f *NetworkManager) Format(item Item) ([]byte, error) {
+	if err := checkEndpointAddrs(item.Configuration); err != nil {
+		return nil, err
+	}
+
+	config := item.Configuration
+	var sb strings.Builder
+
+	fmt.Fprintf(&sb, "[connection]\n")
+	fmt.Fprintf(&sb, "id=%s\n", item.Name)
+	fmt.Fprintf(
+		&sb,
+		"uuid=%s\n",
+		deterministicUUID("networkmanager", identity(item)),
+	)
+	fmt.Fprintf(&sb, "type=wireguard\n")
+	fmt.Fprintf(
+		&sb,
+		"interface-name=%s\n",
+		interfaceName(item.Name, linuxInterfaceNameMaxLen),
+	)
+	fmt.Fprintf(&sb, "autoconnect=false\n")
+
+	fmt.Fprintf(&sb, "\n[wireguard]\n")
+	fmt.Fprintf(&sb, "private-key=%s\n", config.PrivateKey)
+	fmt.Fprintf(&sb, "private-key-flags=0\n")
+	if config.ListenPort > 0 {
+		fmt.Fprintf(&sb, "listen-port=%d\n", config.ListenPort)
+	}
+	if config.FwMark > 0 {
+		fmt.Fprintf(&sb, "fwmark=%d\n", config.FwMark)
+	}
+	if config.MTU > 0 {
+		fmt.Fprintf(&sb, "mtu=%d\n", config.MTU)
+	}
+
+	var allowedIPs []netip.Prefix
+
+	for _, peer := range config.Peers {
+		allowedIPs = append(allowedIPs, peer.AllowedIPs...)
+
+		fmt.Fprintf(&sb, "\n[wireguard-peer.%s]\n", peer.PublicKey)
+		if peer.Endpoint.IsValid() {
+			fmt.Fprintf(&sb, "endpoint=%s\n", peer.Endpoint)
+		}
+		if peer.PresharedKey != "" {
+			fmt.Fprintf(&sb, "preshared-key=%s\n", peer.PresharedKey)
+			fmt.Fprintf(&sb, "preshared-key-flags=0\n")
+		}
+		if peer.PersistentKeepalive > 0 {
+			fmt.Fprintf(
+				&sb,
+				"persistent-keepalive=%d\n",
+				peer.PersistentKeepalive,
+			)
+		}
+		fmt.Fprintf(&sb, "allowed-ips=%s\n", keyfileList(peer.AllowedIPs))
+	}
+
+	addressesV4, addressesV6 := splitPrefixes(config.InterfaceAddresses)
+	dnsV4, dnsV6 := splitAddrs(config.DNS)
+	routesV4, routesV6 := splitPrefixes(allowedIPs)
+
+	dnsSearchV4, dnsSearchV6 := config.DNSSearch, []string(nil)
+	if len(addressesV4) == 0 {
+		dnsSearchV4, dnsSearchV6 = nil, config.DNSSearch
+	}
+
+	f.warnDroppedDNS(item, "IPv4", addressesV4, dnsV4)
+	f.warnDroppedDNS(item, "IPv6", addressesV6, dnsV6)
+
+	writeNetworkManagerIPSection(
+		&sb,
+		"ipv4",
+		addressesV4,
+		dnsV4,
+		dnsSearchV4,
+		hasDefaultRoute(routesV4),
+	)
+	writeNetworkManagerIPSection(
+		&sb,
+		"ipv6",
+		addressesV6,
+		dnsV6,
+		dnsSearchV6,
+		hasDefaultRoute(routesV6),
+	)
+
+	return []byte(sb.String()), nil
+}
+
+// FormatBatch writes one keyfile per configuration with the 0600
+// permissions NetworkManager requires.
+func (f *NetworkManager) FormatBatch(items []Item) ([]File, error) {
+	return Each(f, items, networkManagerFileMode)
+}
+
+// warnDroppedDNS reports the DNS servers of an address family the tunnel has
+// no addresses for, which writeNetworkManagerIPSection leaves out.
+func (f *NetworkManager) warnDroppedDNS(
+	item Item,
+	family string,
+	addresses []netip.Prefix,
+	dns []netip.Addr,
+) {
+	if len(addresses) > 0 || len(dns) == 0 {
+		return
+	}
+
+	f.warnf(
+		"dropping DNS servers %s from %s: networkmanager keyfiles need an "+
+			"%s address to use them",
+		joinStrings(dns, ", "),
+		item.Name,
+		family,
+	)
+}
+
+func writeNetworkManagerIPSection(
+	sb *strings.Builder,
+	section string,
+	addresses []netip.Prefix,
+	dns []netip.Addr,
+	dnsSearch []string,
+	fullTunnel bool,
+) {
+	fmt.Fprintf(sb, "\n[%s]\n", section)
+
+	if len(addresses) == 0 {
+		fmt.Fprintf(sb, "method=disabled\n")
+		return
+	}
+
+	for i, address := range addresses {
+		fmt.Fprintf(sb, "address%d=%s\n", i+1, address)
+	}
+
+	if len(dns) > 0 {
+		fmt.Fprintf(sb, "dns=%s\n", keyfileList(dns))
+
+		priority := networkManagerSplitTunnelDNSPriority
+		if fullTunnel {
+			priority = networkManagerFullTunnelDNSPriority
+		}
+		fmt.Fprintf(sb, "dns-priority=%d\n", priority)
+	}
+
+	if len(dnsSearch) > 0 {
+		fmt.Fprintf(sb, "dns-search=%s;\n", strings.Join(dnsSearch, ";"))
+	}
+
+	fmt.Fprintf(sb, "method=manual\n")
+	fmt.Fprintf(sb, "never-default=%t\n", !fullTunnel)
+}
+
+// keyfileList renders values as a GKeyFile list, which separates and
+// terminates every entry with a semicolon.
+func keyfileList[T stringer](values []T) string {
+	if len(values) == 0 {
+		return ""
+	}
+	return joinStrings(values, ";") + ";"
+}
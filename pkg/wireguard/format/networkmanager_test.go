@@ -0,0 +1,87 @@
+package format
+
+import (
+	"fmt"
+	"io/fs"
+	"net/netip"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNetworkManager(t *testing.T) {
+	t.Parallel()
+
+	t.Run("keyfiles are only readable by their owner", func(t *testing.T) {
+		t.Parallel()
+
+		files, err := NewNetworkManager(NetworkManagerOptions{}).FormatBatch(testItems())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, file := range files {
+			assert.Equal(t, fs.FileMode(0o600), file.Mode)
+		}
+	})
+
+	t.Run("IPv6-only tunnels keep their DNS settings", func(t *testing.T) {
+		t.Parallel()
+
+		item := testItems()[1]
+		item.Configuration.InterfaceAddresses = []netip.Prefix{
+			netip.MustParsePrefix("fc00:bbbb:bbbb:bb01::2/128"),
+		}
+		item.Configuration.DNS = append(
+			item.Configuration.DNS,
+			netip.MustParseAddr("fc00:bbbb:bbbb:bb01::1"),
+		)
+		item.Configuration.DNSSearch = []string{"mullvad.lan"}
+
+		var warnings []string
+		f := NewNetworkManager(NetworkManagerOptions{
+			Warnf: func(format string, v ...any) {
+				warnings = append(warnings, fmt.Sprintf(format, v...))
+			},
+		})
+
+		content, err := f.Format(item)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_, ipv6, _ := strings.Cut(string(content), "[ipv6]\n")
+		assert.Contains(t, ipv6, "dns=fc00:bbbb:bbbb:bb01::1;\n")
+		assert.Contains(t, ipv6, "dns-search=mullvad.lan;\n")
+		assert.Equal(t, []string{
+			"dropping DNS servers 10.64.0.1 from mullvad_1: networkmanager " +
+				"keyfiles need an IPv4 address to use them",
+		}, warnings)
+	})
+
+	t.Run("uuids are derived from the item identity", func(t *testing.T) {
+		t.Parallel()
+
+		items := testItems()
+
+		// uuid.uuid5(namespace, "networkmanager\x00" + identity) in Python.
+		assert.Equal(
+			t,
+			"828615ee-2097-5d5d-9795-b6e9824b6af1",
+			deterministicUUID("networkmanager", identity(items[0])),
+		)
+		assert.NotEqual(
+			t,
+			deterministicUUID("networkmanager", identity(items[0])),
+			deterministicUUID("networkmanager", identity(items[1])),
+		)
+	})
+
+	t.Run("interface names fit the kernel limit", func(t *testing.T) {
+		t.Parallel()
+
+		assert.Equal(t, "nordvpn_0", interfaceName("nordvpn_0", 15))
+		assert.Equal(t, "a_very_long_nam", interfaceName("a.very long name", 15))
+	})
+}
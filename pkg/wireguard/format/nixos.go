@@ -0,0 +1,158 @@
+package format
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// DefaultNixOSKeyDir is where privateKeyFile points at by default, matching
+// the runtime directory of secret managers such as sops-nix and agenix.
+const DefaultNixOSKeyDir = "/run/secrets/wireguard"
+
+// NixOSOptions tunes the NixOS output.
+type NixOSOptions struct {
+	// KeyDir is the directory the privateKeyFile and presharedKeyFile
+	// placeholders point at. Keys are never inlined, since anything in a
+	// Nix expression ends up world-readable in the Nix store.
+	KeyDir string
+	// Autostart starts the tunnel at boot. Off by default, so importing a
+	// whole server list does not bring every tunnel up at once.
+	Autostart bool
+}
+
+// NixOS renders configurations as NixOS modules declaring a
+// networking.wg-quick interface.
+type NixOS struct {
+	options NixOSOptions
+}
+
+// NewNixOS initializes and returns a NixOS formatter.
+func NewNixOS(options NixOSOptions) *NixOS {
+	if options.KeyDir == "" {
+		options.KeyDir = DefaultNixOSKeyDir
+	}
+	return &NixOS{options: options}
+}
+
+func (f *NixOS) Name() string { return "nixos" }
+
+func (f *NixOS) Extension() string { return ".nix" }
+
+// Format renders the module of a single configuration. The private key is
+// expected at <KeyDir>/<name>.key and preshared keys at
+// <KeyDir>/<name>.psk, numbered from the second peer on.
+func (f *NixOS) Format(item Item) ([]byte, error) {
+	config := item.Configuration
+	name := interfaceName(item.Name, linuxInterfaceNameMaxLen)
+	var sb strings.Builder
+
+	fmt.Fprintf(&sb, "{ lib, ... }:\n\n{\n")
+	fmt.Fprintf(
+		&sb,
+		"  networking.wg-quick.interfaces.%s = {\n",
+		nixString(name),
+	)
+	fmt.Fprintf(
+		&sb,
+		"    address = %s;\n",
+		nixList(toStrings(config.InterfaceAddresses)),
+	)
+	if dns := append(toStrings(config.DNS), config.DNSSearch...); len(dns) > 0 {
+		fmt.Fprintf(&sb, "    dns = %s;\n", nixList(dns))
+	}
+	fmt.Fprintf(
+		&sb,
+		"    privateKeyFile = %s;\n",
+		nixString(path.Join(f.options.KeyDir, name+".key")),
+	)
+	if config.ListenPort > 0 {
+		fmt.Fprintf(&sb, "    listenPort = %d;\n", config.ListenPort)
+	}
+	if config.MTU > 0 {
+		fmt.Fprintf(&sb, "    mtu = %d;\n", config.MTU)
+	}
+
+	fmt.Fprintf(&sb, "    peers = [\n")
+	for i, peer := range config.Peers {
+		fmt.Fprintf(&sb, "      {\n")
+		fmt.Fprintf(&sb, "        publicKey = %s;\n", nixString(peer.PublicKey))
+		if peer.PresharedKey != "" {
+			fmt.Fprintf(
+				&sb,
+				"        presharedKeyFile = %s;\n",
+				nixString(path.Join(f.options.KeyDir, nixPSKName(name, i))),
+			)
+		}
+		fmt.Fprintf(
+			&sb,
+			"        allowedIPs = %s;\n",
+			nixList(toStrings(peer.AllowedIPs)),
+		)
+		if endpoint := peer.EndpointString(); endpoint != "" {
+			fmt.Fprintf(
+				&sb,
+				"        endpoint = %s;\n",
+				nixString(endpoint),
+			)
+		}
+		if peer.PersistentKeepalive > 0 {
+			fmt.Fprintf(
+				&sb,
+				"        persistentKeepalive = %d;\n",
+				peer.PersistentKeepalive,
+			)
+		}
+		fmt.Fprintf(&sb, "      }\n")
+	}
+	fmt.Fprintf(&sb, "    ];\n")
+	fmt.Fprintf(&sb, "  };\n\n")
+
+	// wg-quick interfaces are started by wg-quick-<name>.service. Forcing
+	// its wantedBy overrides the module's own autostart default either way.
+	wantedBy := "[ ]"
+	if f.options.Autostart {
+		wantedBy = nixList([]string{"multi-user.target"})
+	}
+	fmt.Fprintf(
+		&sb,
+		"  systemd.services.%s.wantedBy = lib.mkForce %s;\n",
+		nixString("wg-quick-"+name),
+		wantedBy,
+	)
+	fmt.Fprintf(&sb, "}\n")
+
+	return []byte(sb.String()), nil
+}
+
+// FormatBatch writes one module per configuration. The modules only point at
+// the key files, so they are written with mode 0644.
+func (f *NixOS) FormatBatch(items []Item) ([]File, error) {
+	return Each(f, items, publicFileMode)
+}
+
+func nixPSKName(name string, peer int) string {
+	if peer == 0 {
+		return name + ".psk"
+	}
+	return fmt.Sprintf("%s_%d.psk", name, peer)
+}
+
+// nixString double quotes a value and escapes the characters Nix treats
+// specially inside strings, including the start of an interpolation.
+func nixString(value string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, `"`, `\"`, `${`, `\${`)
+	return `"` + replacer.Replace(value) + `"`
+}
+
+func nixList(values []string) string {
+	if len(values) == 0 {
+		return "[ ]"
+	}
+
+	quoted := make([]string, 0, len(values))
+	for _, value := range values {
+		quoted = append(quoted, nixString(value))
+	}
+	return "[ " + strings.Join(quoted, " ") + " ]"
+}
@@ -0,0 +1,47 @@
+package format
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNixOS(t *testing.T) {
+	t.Parallel()
+
+	t.Run("autostart wants the service at boot", func(t *testing.T) {
+		t.Parallel()
+
+		content, err := NewNixOS(NixOSOptions{
+			KeyDir:    "/etc/wireguard",
+			Autostart: true,
+		}).Format(testItems()[1])
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		assert.Contains(
+			t,
+			string(content),
+			`privateKeyFile = "/etc/wireguard/mullvad_1.key";`,
+		)
+		assert.Contains(
+			t,
+			string(content),
+			`presharedKeyFile = "/etc/wireguard/mullvad_1.psk";`,
+		)
+		assert.Contains(
+			t,
+			string(content),
+			`systemd.services."wg-quick-mullvad_1".wantedBy = `+
+				`lib.mkForce [ "multi-user.target" ];`,
+		)
+		assert.NotContains(t, string(content), "OEvHuuMp")
+	})
+
+	t.Run("strings cannot interpolate", func(t *testing.T) {
+		t.Parallel()
+
+		assert.Equal(t, `"a\${b}\"\\"`, nixString(`a${b}"\`))
+	})
+}
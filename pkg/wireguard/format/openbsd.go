@@ -0,0 +1,138 @@
+package format
+
+import (
+	"fmt"
+	"strings"
+)
+
+// netstart(8) fixes up hostname.if files readable by anyone but root and
+// wheel, since they may hold secrets.
+const openBSDFileMode = 0o640
+
+// OpenBSD renders configurations as OpenBSD hostname.wgN files for the
+// native wg(4) driver.
+type OpenBSD struct{}
+
+// NewOpenBSD initializes and returns an OpenBSD formatter.
+func NewOpenBSD() *OpenBSD {
+	return &OpenBSD{}
+}
+
+func (f *OpenBSD) Name() string { return "openbsd" }
+
+// Extension is empty, as the files are named hostname.wgN after the
+// interface they configure, like netstart(8) expects.
+func (f *OpenBSD) Extension() string { return "" }
+
+// Format renders the hostname.if file of a single configuration on wg0.
+func (f *OpenBSD) Format(item Item) ([]byte, error) {
+	if err := checkEndpointAddrs(item.Configuration); err != nil {
+		return nil, err
+	}
+	return []byte(f.hostname(item, "wg0")), nil
+}
+
+// hostname renders the hostname.if file of the interface called name. The
+// endpoints of default routes are kept on the existing gateway by the script
+// of bsdEndpointScript, which it runs from /etc.
+func (f *OpenBSD) hostname(item Item, name string) string {
+	config := item.Configuration
+	var sb strings.Builder
+
+	fmt.Fprintf(&sb, "description %q\n", item.Name)
+	fmt.Fprintf(&sb, "wgkey %s\n", config.PrivateKey)
+	if config.ListenPort > 0 {
+		fmt.Fprintf(&sb, "wgport %d\n", config.ListenPort)
+	}
+
+	for _, peer := range config.Peers {
+		fmt.Fprintf(&sb, "wgpeer %s", peer.PublicKey)
+		if peer.PresharedKey != "" {
+			fmt.Fprintf(&sb, " wgpsk %s", peer.PresharedKey)
+		}
+		if peer.Endpoint.IsValid() {
+			fmt.Fprintf(
+				&sb,
+				" wgendpoint %s %d",
+				peer.Endpoint.Addr(),
+				peer.Endpoint.Port(),
+			)
+		}
+		for _, allowedIP := range peer.AllowedIPs {
+			fmt.Fprintf(&sb, " wgaip %s", allowedIP)
+		}
+		if peer.PersistentKeepalive > 0 {
+			fmt.Fprintf(&sb, " wgpka %d", peer.PersistentKeepalive)
+		}
+		fmt.Fprintf(&sb, "\n")
+	}
+
+	addressesV4, addressesV6 := splitPrefixes(config.InterfaceAddresses)
+	for _, address := range addressesV4 {
+		fmt.Fprintf(&sb, "inet %s\n", address)
+	}
+	for _, address := range addressesV6 {
+		fmt.Fprintf(&sb, "inet6 %s\n", address)
+	}
+	if config.MTU > 0 {
+		fmt.Fprintf(&sb, "mtu %d\n", config.MTU)
+	}
+	fmt.Fprintf(&sb, "up\n")
+
+	routes, halves, bypass := bsdRoutes(config)
+	if len(bypass) > 0 {
+		fmt.Fprintf(
+			&sb,
+			"!/bin/sh /etc/%s\n",
+			bsdEndpointScriptName(f.Name(), name),
+		)
+	}
+	for _, route := range append(routes, halves...) {
+		// Routes point at the tunnel's own address of the route's family,
+		// which -iface turns into an interface route.
+		gateway := addressesV4[0]
+		if route.Addr().Is6() {
+			gateway = addressesV6[0]
+		}
+
+		fmt.Fprintf(
+			&sb,
+			"!route -q add %s %s -iface %s\n",
+			bsdFamily(route.Addr()),
+			route,
+			gateway.Addr(),
+		)
+	}
+
+	return sb.String()
+}
+
+// FormatBatch writes a hostname.wgN file per configuration, numbering the
+// interfaces in item order, plus the endpoint script of each interface
+// routing a default route through the tunnel.
+func (f *OpenBSD) FormatBatch(items []Item) ([]File, error) {
+	if err := checkItemEndpointAddrs(f, items); err != nil {
+		return nil, err
+	}
+
+	files := make([]File, 0, len(items))
+
+	for i, item := range items {
+		name := fmt.Sprintf("wg%d", i)
+
+		files = append(files, File{
+			Name:    "hostname." + name,
+			Content: []byte(f.hostname(item, name)),
+			Mode:    openBSDFileMode,
+		})
+
+		if _, _, bypass := bsdRoutes(item.Configuration); len(bypass) > 0 {
+			files = append(files, File{
+				Name:    bsdEndpointScriptName(f.Name(), name),
+				Content: bsdEndpointScript(name, bypass, nil),
+			})
+		}
+	}
+
+	return files, nil
+}
@@ -0,0 +1,294 @@
+package format
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	// netifd names the WireGuard device after the logical interface, so
+	// the kernel's limit applies to it.
+	openWrtInterfaceNameMaxLen = linuxInterfaceNameMaxLen
+	// fw3 refuses zone names longer than 11 characters.
+	openWrtZoneNameMaxLen = 11
+)
+
+// OpenWrtOptions tunes the OpenWrt output.
+type OpenWrtOptions struct {
+	// Batch renders `uci batch` commands instead of /etc/config fragments.
+	Batch bool
+	// Firewall adds a firewall zone for the tunnel that masquerades
+	// traffic forwarded from the lan zone.
+	Firewall bool
+	// Prefix is prepended to every interface and section name so that many
+	// generated configurations fit on one router.
+	Prefix string
+}
+
+// OpenWrt renders configurations as OpenWrt UCI configuration for netifd's
+// wireguard protocol.
+type OpenWrt struct {
+	options OpenWrtOptions
+}
+
+// NewOpenWrt initializes and returns an OpenWrt formatter.
+func NewOpenWrt(options OpenWrtOptions) *OpenWrt {
+	return &OpenWrt{options: options}
+}
+
+func (f *OpenWrt) Name() string { return "openwrt" }
+
+// Extension is .uci for batch commands and .uci.network for fragments of
+// /etc/config/network, which keeps them apart from networkd's .network files.
+func (f *OpenWrt) Extension() string {
+	if f.options.Batch {
+		return ".uci"
+	}
+	return ".uci.network"
+}
+
+// Format renders the network configuration of an item. In batch mode the
+// firewall zone is part of the same script.
+func (f *OpenWrt) Format(item Item) ([]byte, error) {
+	if err := checkEndpointAddrs(item.Configuration); err != nil {
+		return nil, err
+	}
+
+	network := f.networkSections(item)
+
+	if !f.options.Batch {
+		return []byte(renderUCIConfig(network)), nil
+	}
+
+	script := renderUCIBatch("network", network)
+	if f.options.Firewall {
+		script += renderUCIBatch("firewall", f.firewallSections(item))
+	}
+
+	return []byte(script), nil
+}
+
+// FormatBatch writes one file per configuration, plus a .uci.firewall
+// fragment of /etc/config/firewall when the firewall zone is enabled outside
+// batch mode. The fragments hold no keys and are written with mode 0644.
+func (f *OpenWrt) FormatBatch(items []Item) ([]File, error) {
+	files, err := Each(f, items, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	if f.options.Batch || !f.options.Firewall {
+		return files, nil
+	}
+
+	for _, item := range items {
+		files = append(files, File{
+			Name:    item.Name + ".uci.firewall",
+			Content: []byte(renderUCIConfig(f.firewallSections(item))),
+			Mode:    publicFileMode,
+		})
+	}
+
+	return files, nil
+}
+
+func (f *OpenWrt) interfaceName(item Item) string {
+	name := f.options.Prefix + item.Name
+	return openWrtShortName(uciName(name, len(name)), openWrtInterfaceNameMaxLen)
+}
+
+func (f *OpenWrt) networkSections(item Item) []uciSection {
+	config := item.Configuration
+	name := f.interfaceName(item)
+
+	iface := uciSection{Type: "interface", Name: name}
+	iface.option("proto", "wireguard")
+	iface.option("private_key", config.PrivateKey)
+	if config.ListenPort > 0 {
+		iface.option("listen_port", strconv.Itoa(int(config.ListenPort)))
+	}
+	if config.FwMark > 0 {
+		iface.option("fwmark", fmt.Sprintf("0x%x", config.FwMark))
+	}
+	if config.MTU > 0 {
+		iface.option("mtu", strconv.Itoa(int(config.MTU)))
+	}
+	for _, address := range config.InterfaceAddresses {
+		iface.list("addresses", address.String())
+	}
+	for _, dns := range config.DNS {
+		iface.list("dns", dns.String())
+	}
+	for _, search := range config.DNSSearch {
+		iface.list("dns_search", search)
+	}
+
+	sections := []uciSection{iface}
+
+	for i, peer := range config.Peers {
+		section := uciSection{
+			Type: "wireguard_" + name,
+			Name: fmt.Sprintf("%s_peer%d", name, i),
+		}
+		section.option("description", item.Name)
+		section.option("public_key", peer.PublicKey)
+		if peer.PresharedKey != "" {
+			section.option("preshared_key", peer.PresharedKey)
+		}
+		for _, allowedIP := range peer.AllowedIPs {
+			section.list("allowed_ips", allowedIP.String())
+		}
+		section.option("route_allowed_ips", "1")
+		if peer.Endpoint.IsValid() {
+			section.option("endpoint_host", peer.Endpoint.Addr().String())
+			section.option(
+				"endpoint_port",
+				strconv.Itoa(int(peer.Endpoint.Port())),
+			)
+		}
+		if peer.PersistentKeepalive > 0 {
+			section.option(
+				"persistent_keepalive",
+				strconv.Itoa(int(peer.PersistentKeepalive)),
+			)
+		}
+
+		sections = append(sections, section)
+	}
+
+	return sections
+}
+
+func (f *OpenWrt) firewallSections(item Item) []uciSection {
+	network := f.interfaceName(item)
+	zone := openWrtShortName(network, openWrtZoneNameMaxLen)
+
+	zoneSection := uciSection{Type: "zone", Name: zone}
+	zoneSection.option("name", zone)
+	zoneSection.option("input", "REJECT")
+	zoneSection.option("output", "ACCEPT")
+	zoneSection.option("forward", "REJECT")
+	zoneSection.option("masq", "1")
+	zoneSection.option("mtu_fix", "1")
+	zoneSection.list("network", network)
+
+	forwarding := uciSection{Type: "forwarding", Name: zone + "_lan"}
+	forwarding.option("src", "lan")
+	forwarding.option("dest", zone)
+
+	return []uciSection{zoneSection, forwarding}
+}
+
+// openWrtShortName shortens a name to at most maxLen characters. Names that
+// do not fit keep their start and get a short hash of the full name appended,
+// so prefixed names that only differ at the end stay distinct. Interface
+// names go through it, which keeps the peer sections named after them
+// distinct too, and so do the firewall zones named after the interfaces.
+func openWrtShortName(name string, maxLen int) string {
+	if len(name) <= maxLen {
+		return name
+	}
+
+	sum := sha256.Sum256([]byte(name))
+	suffix := hex.EncodeToString(sum[:])[:4]
+
+	return name[:maxLen-len(suffix)-1] + "_" + suffix
+}
+
+// uciName turns name into a UCI section name of at most maxLen characters.
+// UCI only allows letters, digits and underscores.
+func uciName(name string, maxLen int) string {
+	return strings.ReplaceAll(interfaceName(name, maxLen), "-", "_")
+}
+
+type uciOption struct {
+	List  bool
+	Key   string
+	Value string
+}
+
+type uciSection struct {
+	Type    string
+	Name    string
+	Options []uciOption
+}
+
+func (s *uciSection) option(key, value string) {
+	s.Options = append(s.Options, uciOption{Key: key, Value: value})
+}
+
+func (s *uciSection) list(key, value string) {
+	s.Options = append(s.Options, uciOption{List: true, Key: key, Value: value})
+}
+
+// renderUCIConfig renders sections in the syntax of the files below
+// /etc/config.
+func renderUCIConfig(sections []uciSection) string {
+	var sb strings.Builder
+
+	for i, section := range sections {
+		if i > 0 {
+			sb.WriteString("\n")
+		}
+
+		fmt.Fprintf(
+			&sb,
+			"config %s %s\n",
+			section.Type,
+			uciQuote(section.Name),
+		)
+		for _, option := range section.Options {
+			keyword := "option"
+			if option.List {
+				keyword = "list"
+			}
+			fmt.Fprintf(
+				&sb,
+				"\t%s %s %s\n",
+				keyword,
+				option.Key,
+				uciQuote(option.Value),
+			)
+		}
+	}
+
+	return sb.String()
+}
+
+// renderUCIBatch renders sections as `uci batch` commands for the given
+// config and commits them.
+func renderUCIBatch(config string, sections []uciSection) string {
+	var sb strings.Builder
+
+	for _, section := range sections {
+		path := config + "." + section.Name
+
+		fmt.Fprintf(&sb, "set %s=%s\n", path, section.Type)
+		for _, option := range section.Options {
+			command := "set"
+			if option.List {
+				command = "add_list"
+			}
+			fmt.Fprintf(
+				&sb,
+				"%s %s.%s=%s\n",
+				command,
+				path,
+				option.Key,
+				uciQuote(option.Value),
+			)
+		}
+	}
+
+	fmt.Fprintf(&sb, "commit %s\n", config)
+
+	return sb.String()
+}
+
+// uciQuote single quotes a value the way uci export does.
+func uciQuote(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+}
@@ -0,0 +1,131 @@
+package format
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOpenWrt(t *testing.T) {
+	t.Parallel()
+
+	t.Run("batch mode commits network and firewall", func(t *testing.T) {
+		t.Parallel()
+
+		files, err := NewOpenWrt(OpenWrtOptions{
+			Batch:    true,
+			Firewall: true,
+			Prefix:   "wg_",
+		}).FormatBatch(testItems()[:1])
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		assert.Len(t, files, 1)
+		assert.Equal(t, "nordvpn_0.uci", files[0].Name)
+		assertGolden(
+			t,
+			"testdata/openwrt/batch/nordvpn_0.uci.golden",
+			files[0].Content,
+		)
+	})
+
+	t.Run("fragments get a separate firewall file", func(t *testing.T) {
+		t.Parallel()
+
+		files, err := NewOpenWrt(OpenWrtOptions{Firewall: true}).
+			FormatBatch(testItems())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		assert.Equal(
+			t,
+			[]string{
+				"nordvpn_0.uci.network",
+				"mullvad_1.uci.network",
+				"nordvpn_0.uci.firewall",
+				"mullvad_1.uci.firewall",
+			},
+			[]string{files[0].Name, files[1].Name, files[2].Name, files[3].Name},
+		)
+		assert.Contains(
+			t,
+			string(files[2].Content),
+			"config zone 'nordvpn_0'\n\toption name 'nordvpn_0'\n",
+		)
+	})
+
+	t.Run("fragments do not clash with networkd files", func(t *testing.T) {
+		t.Parallel()
+
+		openWrt, err := NewOpenWrt(OpenWrtOptions{Firewall: true}).
+			FormatBatch(testItems())
+		if err != nil {
+			t.Fatal(err)
+		}
+		networkd, err := NewNetworkd(NetworkdOptions{}).FormatBatch(testItems())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, file := range openWrt {
+			clashes := slices.ContainsFunc(networkd, func(other File) bool {
+				return other.Name == file.Name
+			})
+			assert.False(t, clashes, file.Name)
+		}
+	})
+
+	t.Run("long prefixed names stay distinct", func(t *testing.T) {
+		t.Parallel()
+
+		var items []Item
+		for i := range 12 {
+			item := testItems()[0]
+			item.Name = fmt.Sprintf("nordvpn_%d", i)
+			items = append(items, item)
+		}
+
+		files, err := NewOpenWrt(OpenWrtOptions{
+			Firewall: true,
+			Prefix:   "home_wg_",
+		}).FormatBatch(items)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		sections := regexp.MustCompile(`(?m)^config (\S+) '([^']+)'$`)
+		seen := map[string]bool{}
+
+		for _, file := range files {
+			for _, match := range sections.FindAllStringSubmatch(
+				string(file.Content),
+				-1,
+			) {
+				kind, name := match[1], match[2]
+				assert.False(t, seen[name], "%s is used twice", name)
+				seen[name] = true
+
+				switch kind {
+				case "interface":
+					assert.LessOrEqual(t, len(name), openWrtInterfaceNameMaxLen)
+				case "zone":
+					assert.LessOrEqual(t, len(name), openWrtZoneNameMaxLen)
+				}
+			}
+		}
+
+		// An interface, a peer, a zone and a forwarding per item.
+		assert.Len(t, seen, 4*len(items))
+	})
+
+	t.Run("names only contain characters uci accepts", func(t *testing.T) {
+		t.Parallel()
+
+		assert.Equal(t, "my_vpn_0", uciName("my-vpn.0", 15))
+	})
+}
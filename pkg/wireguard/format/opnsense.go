@@ -0,0 +1,192 @@
+package format
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/xbnz/wireguard-config-generator/pkg/wireguard"
+)
+
+// OPNsense renders configurations as the config.xml fragment of the OPNsense
+// WireGuard plugin: one instance, its peer and a gateway per tunnel. Every
+// entry carries a UUID derived from the configuration identity, so importing
+// a regenerated fragment updates entries instead of duplicating them.
+type OPNsense struct{}
+
+// NewOPNsense initializes and returns an OPNsense formatter.
+func NewOPNsense() *OPNsense {
+	return &OPNsense{}
+}
+
+func (f *OPNsense) Name() string { return "opnsense" }
+
+func (f *OPNsense) Extension() string { return ".xml" }
+
+// Format renders a fragment holding a single tunnel.
+func (f *OPNsense) Format(item Item) ([]byte, error) {
+	return f.render([]Item{item})
+}
+
+// FormatBatch writes all tunnels into a single opnsense.xml. Instances are
+// numbered by identity, so a reordered server list keeps the instance and
+// wgN device of every server.
+func (f *OPNsense) FormatBatch(items []Item) ([]File, error) {
+	content, err := f.render(items)
+	if err != nil {
+		return nil, err
+	}
+
+	return []File{{
+		Name:    f.Name() + f.Extension(),
+		Content: content,
+		Mode:    firewallXMLFileMode,
+	}}, nil
+}
+
+type opnsenseDocument struct {
+	XMLName   xml.Name          `xml:"opnsense"`
+	WireGuard opnsenseWireGuard `xml:"OPNsense>wireguard"`
+	Gateways  []opnsenseGateway `xml:"OPNsense>Gateways>gateway_item"`
+}
+
+type opnsenseWireGuard struct {
+	Enabled int              `xml:"general>enabled"`
+	Servers []opnsenseServer `xml:"server>servers>server"`
+	Clients []opnsenseClient `xml:"client>clients>client"`
+}
+
+type opnsenseServer struct {
+	UUID          string `xml:"uuid,attr"`
+	Enabled       int    `xml:"enabled"`
+	Name          string `xml:"name"`
+	Instance      int    `xml:"instance"`
+	PublicKey     string `xml:"pubkey"`
+	PrivateKey    string `xml:"privkey"`
+	Port          string `xml:"port"`
+	MTU           string `xml:"mtu"`
+	DNS           string `xml:"dns"`
+	TunnelAddress string `xml:"tunneladdress"`
+	DisableRoutes int    `xml:"disableroutes"`
+	Gateway       string `xml:"gateway"`
+	Peers         string `xml:"peers"`
+}
+
+type opnsenseClient struct {
+	UUID          string `xml:"uuid,attr"`
+	Enabled       int    `xml:"enabled"`
+	Name          string `xml:"name"`
+	PublicKey     string `xml:"pubkey"`
+	PresharedKey  string `xml:"psk"`
+	TunnelAddress string `xml:"tunneladdress"`
+	ServerAddress string `xml:"serveraddress"`
+	ServerPort    string `xml:"serverport"`
+	Keepalive     string `xml:"keepalive"`
+}
+
+type opnsenseGateway struct {
+	UUID           string `xml:"uuid,attr"`
+	Disabled       int    `xml:"disabled"`
+	Name           string `xml:"name"`
+	Description    string `xml:"descr"`
+	Interface      string `xml:"interface"`
+	IPProtocol     string `xml:"ipprotocol"`
+	Gateway        string `xml:"gateway"`
+	DefaultGW      int    `xml:"defaultgw"`
+	FarGW          int    `xml:"fargw"`
+	MonitorDisable int    `xml:"monitor_disable"`
+}
+
+func (f *OPNsense) render(items []Item) ([]byte, error) {
+	var document opnsenseDocument
+	document.WireGuard.Enabled = 1
+
+	for instance, item := range sortedByIdentity(items) {
+		config := item.Configuration
+		id := identity(item)
+
+		publicKey, err := wireguard.PublicKey(config.PrivateKey)
+		if err != nil {
+			return nil, fmt.Errorf("format %s as opnsense: %w", item.Name, err)
+		}
+		if err := checkEndpointAddrs(config); err != nil {
+			return nil, fmt.Errorf("format %s as opnsense: %w", item.Name, err)
+		}
+
+		var peerUUIDs []string
+		for i, peer := range config.Peers {
+			peerUUID := deterministicUUID(
+				"opnsense",
+				"peer",
+				id,
+				peer.PublicKey,
+			)
+			peerUUIDs = append(peerUUIDs, peerUUID)
+
+			client := opnsenseClient{
+				UUID:          peerUUID,
+				Enabled:       1,
+				Name:          firewallPeerName(item, i),
+				PublicKey:     peer.PublicKey,
+				PresharedKey:  peer.PresharedKey,
+				TunnelAddress: joinStrings(peer.AllowedIPs, ","),
+				Keepalive:     optionalUint(peer.PersistentKeepalive),
+			}
+			if peer.Endpoint.IsValid() {
+				client.ServerAddress = peer.Endpoint.Addr().String()
+				client.ServerPort = strconv.Itoa(int(peer.Endpoint.Port()))
+			}
+
+			document.WireGuard.Clients = append(
+				document.WireGuard.Clients,
+				client,
+			)
+		}
+
+		disableRoutes := 0
+		if config.IsFullTunnel() {
+			// Routing a default route through the tunnel is left to the
+			// gateway, otherwise the tunnel would swallow its own traffic.
+			disableRoutes = 1
+		}
+
+		document.WireGuard.Servers = append(
+			document.WireGuard.Servers,
+			opnsenseServer{
+				UUID:          deterministicUUID("opnsense", "server", id),
+				Enabled:       1,
+				Name:          item.Name,
+				Instance:      instance,
+				PublicKey:     publicKey,
+				PrivateKey:    config.PrivateKey,
+				Port:          optionalUint(config.ListenPort),
+				MTU:           optionalUint(config.MTU),
+				DNS:           joinStrings(config.DNS, ","),
+				TunnelAddress: joinStrings(config.InterfaceAddresses, ","),
+				DisableRoutes: disableRoutes,
+				Peers:         strings.Join(peerUUIDs, ","),
+			},
+		)
+
+		for _, family := range firewallGatewayFamilies(config) {
+			document.Gateways = append(document.Gateways, opnsenseGateway{
+				UUID: deterministicUUID(
+					"opnsense",
+					"gateway",
+					id,
+					family.protocol,
+				),
+				Name:           firewallGatewayName(item, family),
+				Description:    "WireGuard " + item.Name,
+				Interface:      fmt.Sprintf("wg%d", instance),
+				IPProtocol:     family.protocol,
+				Gateway:        "dynamic",
+				FarGW:          1,
+				MonitorDisable: 1,
+			})
+		}
+	}
+
+	return marshalFirewallXML(document)
+}
@@ -0,0 +1,163 @@
+package format
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/netip"
+	"strconv"
+
+	"github.com/xbnz/wireguard-config-generator/pkg/wireguard"
+)
+
+// PfSense renders configurations as the config.xml fragment of the pfSense
+// WireGuard package: a tunnel, its peer and a gateway per configuration.
+// pfSense has no UUIDs, so tunnels are numbered in the order of the servers'
+// public keys. A reordered server list keeps the numbers, while added or
+// removed servers shift the tunnels that sort after them.
+type PfSense struct{}
+
+// NewPfSense initializes and returns a PfSense formatter.
+func NewPfSense() *PfSense {
+	return &PfSense{}
+}
+
+func (f *PfSense) Name() string { return "pfsense" }
+
+func (f *PfSense) Extension() string { return ".xml" }
+
+// Format renders a fragment holding a single tunnel.
+func (f *PfSense) Format(item Item) ([]byte, error) {
+	return f.render([]Item{item})
+}
+
+// FormatBatch writes all tunnels into a single pfsense.xml.
+func (f *PfSense) FormatBatch(items []Item) ([]File, error) {
+	content, err := f.render(items)
+	if err != nil {
+		return nil, err
+	}
+
+	return []File{{
+		Name:    f.Name() + f.Extension(),
+		Content: content,
+		Mode:    firewallXMLFileMode,
+	}}, nil
+}
+
+type pfSenseDocument struct {
+	XMLName  xml.Name         `xml:"pfsense"`
+	Tunnels  []pfSenseTunnel  `xml:"installedpackages>wireguard>tunnels>item"`
+	Peers    []pfSensePeer    `xml:"installedpackages>wireguard>peers>item"`
+	Enable   string           `xml:"installedpackages>wireguard>config>enable"`
+	Gateways []pfSenseGateway `xml:"gateways>gateway_item"`
+}
+
+type pfSenseTunnel struct {
+	Name        string           `xml:"name"`
+	Enabled     string           `xml:"enabled"`
+	Description string           `xml:"descr"`
+	ListenPort  string           `xml:"listenport"`
+	PrivateKey  string           `xml:"privatekey"`
+	PublicKey   string           `xml:"publickey"`
+	MTU         string           `xml:"mtu"`
+	Addresses   []pfSenseAddress `xml:"addresses>row"`
+}
+
+type pfSensePeer struct {
+	Enabled             string           `xml:"enabled"`
+	Tunnel              string           `xml:"tun"`
+	Description         string           `xml:"descr"`
+	Endpoint            string           `xml:"endpoint"`
+	Port                string           `xml:"port"`
+	PersistentKeepalive string           `xml:"persistentkeepalive"`
+	PublicKey           string           `xml:"publickey"`
+	PresharedKey        string           `xml:"presharedkey"`
+	AllowedIPs          []pfSenseAddress `xml:"allowedips>row"`
+}
+
+type pfSenseAddress struct {
+	Address     string `xml:"address"`
+	Mask        int    `xml:"mask"`
+	Description string `xml:"descr"`
+}
+
+type pfSenseGateway struct {
+	Interface   string `xml:"interface"`
+	Gateway     string `xml:"gateway"`
+	Name        string `xml:"name"`
+	Weight      int    `xml:"weight"`
+	IPProtocol  string `xml:"ipprotocol"`
+	Description string `xml:"descr"`
+}
+
+func (f *PfSense) render(items []Item) ([]byte, error) {
+	document := pfSenseDocument{Enable: "on"}
+
+	for i, item := range sortedByIdentity(items) {
+		config := item.Configuration
+		tunnel := fmt.Sprintf("tun_wg%d", i)
+
+		publicKey, err := wireguard.PublicKey(config.PrivateKey)
+		if err != nil {
+			return nil, fmt.Errorf("format %s as pfsense: %w", item.Name, err)
+		}
+		if err := checkEndpointAddrs(config); err != nil {
+			return nil, fmt.Errorf("format %s as pfsense: %w", item.Name, err)
+		}
+
+		document.Tunnels = append(document.Tunnels, pfSenseTunnel{
+			Name:        tunnel,
+			Enabled:     "yes",
+			Description: item.Name,
+			ListenPort:  optionalUint(config.ListenPort),
+			PrivateKey:  config.PrivateKey,
+			PublicKey:   publicKey,
+			MTU:         optionalUint(config.MTU),
+			Addresses:   pfSenseAddresses(config.InterfaceAddresses),
+		})
+
+		for j, peer := range config.Peers {
+			entry := pfSensePeer{
+				Enabled:     "yes",
+				Tunnel:      tunnel,
+				Description: firewallPeerName(item, j),
+				PersistentKeepalive: optionalUint(
+					peer.PersistentKeepalive,
+				),
+				PublicKey:    peer.PublicKey,
+				PresharedKey: peer.PresharedKey,
+				AllowedIPs:   pfSenseAddresses(peer.AllowedIPs),
+			}
+			if peer.Endpoint.IsValid() {
+				entry.Endpoint = peer.Endpoint.Addr().String()
+				entry.Port = strconv.Itoa(int(peer.Endpoint.Port()))
+			}
+
+			document.Peers = append(document.Peers, entry)
+		}
+
+		for _, family := range firewallGatewayFamilies(config) {
+			document.Gateways = append(document.Gateways, pfSenseGateway{
+				Interface:   tunnel,
+				Gateway:     "dynamic",
+				Name:        firewallGatewayName(item, family),
+				Weight:      1,
+				IPProtocol:  family.protocol,
+				Description: "WireGuard " + item.Name,
+			})
+		}
+	}
+
+	return marshalFirewallXML(document)
+}
+
+func pfSenseAddresses(prefixes []netip.Prefix) []pfSenseAddress {
+	addresses := make([]pfSenseAddress, 0, len(prefixes))
+	for _, prefix := range prefixes {
+		addresses = append(addresses, pfSenseAddress{
+			Address: prefix.Addr().String(),
+			Mask:    prefix.Bits(),
+		})
+	}
+	return addresses
+}
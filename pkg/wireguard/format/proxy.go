@@ -0,0 +1,117 @@
+package format
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/xbnz/wireguard-config-generator/pkg/wireguard"
+)
+
+const (
+	// DefaultProxyGroupName is the name of the group holding all servers.
+	DefaultProxyGroupName = "wireguard"
+	// DefaultProxyGroupURL is probed to measure the latency of each server.
+	DefaultProxyGroupURL = "https://www.gstatic.com/generate_204"
+	// DefaultProxyGroupInterval is the time between two latency probes.
+	DefaultProxyGroupInterval = 5 * time.Minute
+)
+
+// Proxy group types shared by the proxy client formatters.
+const (
+	// ProxyGroupURLTest picks the server with the lowest latency.
+	ProxyGroupURLTest = "url-test"
+	// ProxyGroupFallback picks the first server that is reachable.
+	ProxyGroupFallback = "fallback"
+)
+
+// ProxyGroupOptions tunes the group proxy clients such as sing-box and
+// Clash build from all generated servers.
+type ProxyGroupOptions struct {
+	// Type is ProxyGroupURLTest or ProxyGroupFallback.
+	Type string
+	// Name is the tag of the group.
+	Name string
+	// URL is probed through each server to tell whether it is alive.
+	URL string
+	// Interval is the time between two probes.
+	Interval time.Duration
+}
+
+func (o ProxyGroupOptions) withDefaults() ProxyGroupOptions {
+	if o.Type != ProxyGroupFallback {
+		o.Type = ProxyGroupURLTest
+	}
+	if o.Name == "" {
+		o.Name = DefaultProxyGroupName
+	}
+	if o.URL == "" {
+		o.URL = DefaultProxyGroupURL
+	}
+	if o.Interval <= 0 {
+		o.Interval = DefaultProxyGroupInterval
+	}
+	return o
+}
+
+// proxyTags returns a unique, human readable tag per item built from its
+// server's country code, city and hostname, which is what proxy clients
+// show in their server pickers. Items without metadata are tagged with
+// their name.
+func proxyTags(items []Item) []string {
+	tags := make([]string, 0, len(items))
+	seen := map[string]int{}
+
+	for _, item := range items {
+		metadata := item.Server.Metadata
+
+		var parts []string
+		for _, part := range []string{
+			strings.ToUpper(metadata.CountryCode),
+			metadata.City,
+			metadata.Hostname,
+		} {
+			if part != "" {
+				parts = append(parts, part)
+			}
+		}
+
+		tag := item.Name
+		if len(parts) > 0 {
+			tag = strings.Join(parts, " ")
+		}
+
+		seen[tag]++
+		if seen[tag] > 1 {
+			tag = fmt.Sprintf("%s #%d", tag, seen[tag])
+		}
+
+		tags = append(tags, tag)
+	}
+
+	return tags
+}
+
+// proxyPeer returns the single peer proxy clients connect to.
+func proxyPeer(
+	formatter string,
+	config wireguard.Configuration,
+) (wireguard.PeerConfig, error) {
+	if len(config.Peers) == 0 {
+		return wireguard.PeerConfig{}, errors.New(formatter + " needs a peer")
+	}
+
+	if err := checkEndpointAddrs(config); err != nil {
+		return wireguard.PeerConfig{}, err
+	}
+
+	peer := config.Peers[0]
+	if !peer.Endpoint.IsValid() {
+		return wireguard.PeerConfig{}, errors.New(
+			formatter + " needs a peer endpoint",
+		)
+	}
+
+	return peer, nil
+}
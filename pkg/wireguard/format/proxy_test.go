@@ -0,0 +1,63 @@
+package format
+
+import (
+	"net/netip"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProxyTags(t *testing.T) {
+	t.Parallel()
+
+	items := testItems()
+	items = append(items, items[0], NewItem("custom_2", items[0].Configuration))
+
+	assert.Equal(t, []string{
+		"DE Frankfurt de1000.nordvpn.com",
+		"SE Gothenburg se-got-wg-001",
+		"DE Frankfurt de1000.nordvpn.com #2",
+		"custom_2",
+	}, proxyTags(items))
+}
+
+func TestClash(t *testing.T) {
+	t.Parallel()
+
+	t.Run("fallback groups keep their type and interval", func(t *testing.T) {
+		t.Parallel()
+
+		content, err := NewClash(ProxyGroupOptions{
+			Type:     ProxyGroupFallback,
+			Name:     "vpn",
+			Interval: time.Minute,
+		}).Format(testItems()[0])
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		assert.Contains(
+			t,
+			string(content),
+			"proxy-groups:\n"+
+				"  - name: vpn\n"+
+				"    type: fallback\n",
+		)
+		assert.True(t, strings.HasSuffix(string(content), "interval: 60\n"))
+	})
+
+	t.Run("configurations without endpoint are rejected", func(t *testing.T) {
+		t.Parallel()
+
+		item := testItems()[0]
+		item.Configuration.Peers[0].Endpoint = netip.AddrPort{}
+
+		_, err := NewClash(ProxyGroupOptions{}).FormatBatch([]Item{item})
+		assert.ErrorContains(t, err, "clash needs a peer endpoint")
+
+		_, err = NewSingBox(ProxyGroupOptions{}).FormatBatch([]Item{item})
+		assert.ErrorContains(t, err, "sing-box needs a peer endpoint")
+	})
+}
@@ -0,0 +1,146 @@
+package format
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/skip2/go-qrcode"
+)
+
+// DefaultQRSize is the default width and height of QR code images in pixels.
+const DefaultQRSize = 512
+
+// ErrQRTooLarge is returned for configurations that do not fit into a QR
+// code at the chosen error correction level.
+var ErrQRTooLarge = errors.New("config is too large for a QR code")
+
+// TerminalFormatter is implemented by formatters whose files are not meant
+// to be read in a terminal, such as images. FormatTerminal renders a text
+// alternative of a single configuration that is printed instead.
+type TerminalFormatter interface {
+	FormatTerminal(item Item) ([]byte, error)
+}
+
+// QROptions tunes the QR code output.
+type QROptions struct {
+	// Level is the error correction level: low, medium, high or highest.
+	// Higher levels survive more damage but fit less content.
+	Level string
+	// Size is the width and height of the PNG images in pixels.
+	Size int
+	// Warnf reports configurations that are skipped because they are too
+	// large. It has the signature of log.Printf.
+	Warnf func(format string, v ...any)
+}
+
+// QR renders the wg-quick INI of each configuration as a QR code, which the
+// WireGuard mobile apps can import by scanning it.
+type QR struct {
+	level qrcode.RecoveryLevel
+	size  int
+	warnf func(format string, v ...any)
+}
+
+// NewQR initializes and returns a QR formatter. Unknown levels fall back to
+// medium.
+func NewQR(options QROptions) *QR {
+	levels := map[string]qrcode.RecoveryLevel{
+		"low":     qrcode.Low,
+		"medium":  qrcode.Medium,
+		"high":    qrcode.High,
+		"highest": qrcode.Highest,
+	}
+
+	level, ok := levels[options.Level]
+	if !ok {
+		level = qrcode.Medium
+	}
+
+	if options.Size <= 0 {
+		options.Size = DefaultQRSize
+	}
+
+	if options.Warnf == nil {
+		options.Warnf = func(string, ...any) {}
+	}
+
+	return &QR{level: level, size: options.Size, warnf: options.Warnf}
+}
+
+func (f *QR) Name() string { return "qr" }
+
+func (f *QR) Extension() string { return ".png" }
+
+// Format renders a PNG image of the configuration's QR code.
+func (f *QR) Format(item Item) ([]byte, error) {
+	code, err := f.encode(item)
+	if err != nil {
+		return nil, err
+	}
+
+	return code.PNG(f.size)
+}
+
+// FormatTerminal renders the QR code with UTF-8 half blocks, two modules per
+// character, so it can be scanned straight off the screen.
+func (f *QR) FormatTerminal(item Item) ([]byte, error) {
+	code, err := f.encode(item)
+	if err != nil {
+		return nil, err
+	}
+
+	return []byte(code.ToSmallString(false)), nil
+}
+
+// FormatBatch writes one PNG image per configuration. Configurations that
+// are too large are reported through Warnf and skipped.
+func (f *QR) FormatBatch(items []Item) ([]File, error) {
+	var fitting []Item
+
+	for _, item := range items {
+		_, err := f.encode(item)
+		if errors.Is(err, ErrQRTooLarge) {
+			f.warnf("skipping QR code for %s: %v", item.Name, err)
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("format %s as qr: %w", item.Name, err)
+		}
+
+		fitting = append(fitting, item)
+	}
+
+	return Each(f, fitting, 0)
+}
+
+func (f *QR) encode(item Item) (*qrcode.QRCode, error) {
+	ini, err := item.Configuration.ToINIFormat()
+	if err != nil {
+		return nil, err
+	}
+
+	code, err := qrcode.New(ini, f.level)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"%w at error correction level %s (%d bytes)",
+			ErrQRTooLarge,
+			f.levelName(),
+			len(ini),
+		)
+	}
+
+	return code, nil
+}
+
+func (f *QR) levelName() string {
+	switch f.level {
+	case qrcode.Low:
+		return "low"
+	case qrcode.High:
+		return "high"
+	case qrcode.Highest:
+		return "highest"
+	default:
+		return "medium"
+	}
+}
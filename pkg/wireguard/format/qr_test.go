@@ -0,0 +1,79 @@
+package format
+
+import (
+	"fmt"
+	"net/netip"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/xbnz/wireguard-config-generator/pkg/wireguard"
+)
+
+func TestQR(t *testing.T) {
+	t.Parallel()
+
+	t.Run("configs are rendered as png images", func(t *testing.T) {
+		t.Parallel()
+
+		files, err := NewQR(QROptions{}).FormatBatch(testItems())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		assert.Len(t, files, 2)
+		assert.Equal(t, "nordvpn_0.png", files[0].Name)
+		assert.Equal(t, "\x89PNG\r\n\x1a\n", string(files[0].Content[:8]))
+	})
+
+	t.Run("terminal output uses half blocks", func(t *testing.T) {
+		t.Parallel()
+
+		content, err := NewQR(QROptions{}).FormatTerminal(testItems()[0])
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		assert.Contains(t, string(content), "█")
+		assert.Contains(t, string(content), "▀")
+	})
+
+	t.Run("oversized configs are skipped with a warning", func(t *testing.T) {
+		t.Parallel()
+
+		item := testItems()[0]
+		for i := range 40 {
+			item.Configuration.Peers = append(
+				item.Configuration.Peers,
+				wireguard.NewPeerConfig(
+					"qIhtTW9K4iXWFo5Q4dOPdXg8/xubXr9yEGoN55D8xnA=",
+					netip.AddrPortFrom(
+						netip.AddrFrom4([4]byte{10, 0, 0, byte(i)}),
+						51820,
+					),
+					[]netip.Prefix{netip.MustParsePrefix("0.0.0.0/0")},
+					25,
+				),
+			)
+		}
+
+		var warnings []string
+		files, err := NewQR(QROptions{
+			Level: "highest",
+			Warnf: func(format string, v ...any) {
+				warnings = append(warnings, fmt.Sprintf(format, v...))
+			},
+		}).FormatBatch([]Item{item, testItems()[1]})
+
+		assert.NoError(t, err)
+		assert.Len(t, files, 1)
+		assert.Equal(t, "mullvad_1.png", files[0].Name)
+		assert.Len(t, warnings, 1)
+		assert.Contains(
+			t,
+			warnings[0],
+			"skipping QR code for nordvpn_0: config is too large for a QR "+
+				"code at error correction level highest",
+		)
+	})
+}
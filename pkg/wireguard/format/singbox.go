@@ -0,0 +1,113 @@
+package format
+
+import (
+	"fmt"
+
+	"github.com/xbnz/wireguard-config-generator/pkg/wireguard"
+)
+
+// SingBox renders configurations as a sing-box configuration fragment: a
+// WireGuard endpoint per server and a urltest outbound grouping all of them.
+type SingBox struct {
+	options ProxyGroupOptions
+}
+
+// NewSingBox initializes and returns a SingBox formatter. sing-box has no
+// fallback group, so the group is always a urltest outbound, which moves
+// away from failing servers as well.
+func NewSingBox(options ProxyGroupOptions) *SingBox {
+	return &SingBox{options: options.withDefaults()}
+}
+
+func (f *SingBox) Name() string { return "sing-box" }
+
+func (f *SingBox) Extension() string { return ".json" }
+
+// Format renders a fragment holding a single endpoint and its group.
+func (f *SingBox) Format(item Item) ([]byte, error) {
+	return f.render([]Item{item})
+}
+
+// FormatBatch writes all endpoints and their group into a single
+// sing-box.json.
+func (f *SingBox) FormatBatch(items []Item) ([]File, error) {
+	content, err := f.render(items)
+	if err != nil {
+		return nil, err
+	}
+
+	return []File{{Name: f.Name() + f.Extension(), Content: content}}, nil
+}
+
+type singBoxDocument struct {
+	Endpoints []singBoxEndpoint `json:"endpoints"`
+	Outbounds []singBoxURLTest  `json:"outbounds"`
+}
+
+type singBoxEndpoint struct {
+	Type       string        `json:"type"`
+	Tag        string        `json:"tag"`
+	System     bool          `json:"system"`
+	MTU        uint16        `json:"mtu,omitempty"`
+	Address    []string      `json:"address"`
+	PrivateKey string        `json:"private_key"`
+	Peers      []singBoxPeer `json:"peers"`
+}
+
+type singBoxPeer struct {
+	Address      string   `json:"address"`
+	Port         uint16   `json:"port"`
+	PublicKey    string   `json:"public_key"`
+	PreSharedKey string   `json:"pre_shared_key,omitempty"`
+	AllowedIPs   []string `json:"allowed_ips"`
+	Keepalive    uint16   `json:"persistent_keepalive_interval,omitempty"`
+}
+
+type singBoxURLTest struct {
+	Type      string   `json:"type"`
+	Tag       string   `json:"tag"`
+	Outbounds []string `json:"outbounds"`
+	URL       string   `json:"url"`
+	Interval  string   `json:"interval"`
+}
+
+func (f *SingBox) render(items []Item) ([]byte, error) {
+	tags := proxyTags(items)
+	document := singBoxDocument{
+		Endpoints: make([]singBoxEndpoint, 0, len(items)),
+		Outbounds: []singBoxURLTest{{
+			Type:      "urltest",
+			Tag:       f.options.Name,
+			Outbounds: tags,
+			URL:       f.options.URL,
+			Interval:  f.options.Interval.String(),
+		}},
+	}
+
+	for i, item := range items {
+		config := item.Configuration
+
+		peer, err := proxyPeer("sing-box", config)
+		if err != nil {
+			return nil, fmt.Errorf("format %s as sing-box: %w", item.Name, err)
+		}
+
+		document.Endpoints = append(document.Endpoints, singBoxEndpoint{
+			Type:       "wireguard",
+			Tag:        tags[i],
+			MTU:        config.MTU,
+			Address:    toStrings(config.InterfaceAddresses),
+			PrivateKey: config.PrivateKey,
+			Peers: []singBoxPeer{{
+				Address:      peer.Endpoint.Addr().String(),
+				Port:         peer.Endpoint.Port(),
+				PublicKey:    peer.PublicKey,
+				PreSharedKey: peer.PresharedKey,
+				AllowedIPs:   toStrings(peer.AllowedIPs),
+				Keepalive:    peer.PersistentKeepalive,
+			}},
+		})
+	}
+
+	return wireguard.MarshalJSON(document)
+}
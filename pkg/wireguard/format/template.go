@@ -0,0 +1,178 @@
+package format
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/netip"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"text/template"
+
+	"github.com/xbnz/wireguard-config-generator/pkg/wireguard"
+)
+
+// defaultTemplateExtension is used for templates whose file name does not
+// tell what they render.
+const defaultTemplateExtension = ".txt"
+
+// TemplateOptions configures the template output.
+type TemplateOptions struct {
+	// Path is the file the template was read from. Its name minus a .tmpl
+	// suffix decides the extension of the written files, so ini.conf.tmpl
+	// writes .conf files.
+	Path string
+	// Text is the text/template source executed for every configuration
+	// with a TemplateData.
+	Text string
+}
+
+// TemplateData is what templates are executed with. It embeds the Item, so
+// templates reach the configuration as .Configuration and the server
+// metadata as .Server.Metadata.
+type TemplateData struct {
+	Item
+	// Index is the position of the configuration in the run, starting at 0.
+	Index int
+}
+
+// Template renders configurations with a user-supplied text/template, for
+// targets that have no formatter of their own.
+type Template struct {
+	options TemplateOptions
+}
+
+// NewTemplate initializes and returns a Template formatter.
+func NewTemplate(options TemplateOptions) *Template {
+	return &Template{options: options}
+}
+
+func (f *Template) Name() string { return "template" }
+
+func (f *Template) Extension() string {
+	name := strings.TrimSuffix(filepath.Base(f.options.Path), ".tmpl")
+	if extension := filepath.Ext(name); len(extension) > 1 {
+		return extension
+	}
+	return defaultTemplateExtension
+}
+
+// Format executes the template for a single configuration at index 0.
+func (f *Template) Format(item Item) ([]byte, error) {
+	tmpl, err := f.parse()
+	if err != nil {
+		return nil, err
+	}
+	return f.execute(tmpl, TemplateData{Item: item})
+}
+
+// FormatBatch executes the template once per configuration and writes one
+// file for each.
+func (f *Template) FormatBatch(items []Item) ([]File, error) {
+	tmpl, err := f.parse()
+	if err != nil {
+		return nil, err
+	}
+
+	files := make([]File, 0, len(items))
+
+	for i, item := range items {
+		content, err := f.execute(tmpl, TemplateData{Item: item, Index: i})
+		if err != nil {
+			return nil, fmt.Errorf(
+				"format %s as %s: %w",
+				item.Name,
+				f.Name(),
+				err,
+			)
+		}
+
+		files = append(files, File{
+			Name:    item.Name + f.Extension(),
+			Content: content,
+		})
+	}
+
+	return files, nil
+}
+
+func (f *Template) parse() (*template.Template, error) {
+	if f.options.Text == "" {
+		return nil, errors.New("no template given")
+	}
+
+	tmpl, err := template.New(filepath.Base(f.options.Path)).
+		Option("missingkey=error").
+		Funcs(templateFuncs).
+		Parse(f.options.Text)
+	if err != nil {
+		return nil, fmt.Errorf("parse template: %w", err)
+	}
+
+	return tmpl, nil
+}
+
+func (f *Template) execute(
+	tmpl *template.Template,
+	data TemplateData,
+) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return nil, fmt.Errorf("execute template: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// templateFuncs are the helpers available to templates on top of the
+// text/template builtins.
+var templateFuncs = template.FuncMap{
+	"join":      templateJoin,
+	"hexKey":    wireguard.HexKey,
+	"publicKey": wireguard.PublicKey,
+	"ipv4":      templateFamily(true),
+	"ipv6":      templateFamily(false),
+}
+
+// templateJoin joins the elements of a slice, such as addresses or
+// prefixes, with sep. The separator comes first so that lists can be piped
+// in: {{ .Configuration.DNS | join ", " }}.
+func templateJoin(sep string, values any) (string, error) {
+	value := reflect.ValueOf(values)
+	if value.Kind() != reflect.Slice && value.Kind() != reflect.Array {
+		return "", fmt.Errorf("cannot join %T", values)
+	}
+
+	parts := make([]string, 0, value.Len())
+	for i := range value.Len() {
+		parts = append(parts, fmt.Sprint(value.Index(i).Interface()))
+	}
+
+	return strings.Join(parts, sep), nil
+}
+
+// templateFamily returns a helper that keeps the IPv4 or the IPv6 members of
+// a list of prefixes or addresses.
+func templateFamily(v4 bool) func(values any) (any, error) {
+	return func(values any) (any, error) {
+		switch values := values.(type) {
+		case []netip.Prefix:
+			prefixesV4, prefixesV6 := splitPrefixes(values)
+			if v4 {
+				return prefixesV4, nil
+			}
+			return prefixesV6, nil
+		case []netip.Addr:
+			addrsV4, addrsV6 := splitAddrs(values)
+			if v4 {
+				return addrsV4, nil
+			}
+			return addrsV6, nil
+		default:
+			return nil, fmt.Errorf(
+				"cannot split %T by address family",
+				values,
+			)
+		}
+	}
+}
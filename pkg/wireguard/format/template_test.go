@@ -0,0 +1,154 @@
+package format
+
+import (
+	"net/netip"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/xbnz/wireguard-config-generator/pkg/wireguard"
+)
+
+func TestTemplate(t *testing.T) {
+	t.Parallel()
+
+	t.Run("bundled examples recreate their formats", func(t *testing.T) {
+		t.Parallel()
+
+		loose, err := wireguard.ParseINI(strings.NewReader(strings.Join(
+			[]string{
+				"[Interface]",
+				"PrivateKey = OEvHuuMpALNf7ZZkzUSGbT8vkj89aHrhLyqZlIn4rPU=",
+				"Address = 10.5.0.2/32, fd00::2/128",
+				"DNS = 103.86.96.100, corp.example",
+				"ListenPort = 51820",
+				"FwMark = 51820",
+				"Jc = 4",
+				"Jmin = 8",
+				"Jmax = 80",
+				"# managed",
+				"Table = off #for now",
+				"",
+				"[Peer]",
+				"PublicKey = qIhtTW9K4iXWFo5Q4dOPdXg8/xubXr9yEGoN55D8xnA=",
+				"AllowedIPs = 10.0.0.0/8",
+				"# no endpoint",
+			},
+			"\n",
+		)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		items := append(testItems(), NewItem("loose", loose))
+
+		for _, example := range []struct {
+			path      string
+			formatter Formatter
+		}{
+			{"ini.conf.tmpl", NewINI()},
+			{"ipc.ipc.tmpl", NewIPC()},
+		} {
+			text, err := os.ReadFile(filepath.Join("templates", example.path))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			f := NewTemplate(TemplateOptions{
+				Path: example.path,
+				Text: string(text),
+			})
+			assert.Equal(t, example.formatter.Extension(), f.Extension())
+
+			files, err := f.FormatBatch(items)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			expected, err := example.formatter.FormatBatch(items)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for i := range expected {
+				if example.path == "ipc.ipc.tmpl" && i == len(expected)-1 {
+					// The ipc example leaves out AmneziaWG parameters.
+					continue
+				}
+				assert.Equal(
+					t,
+					string(expected[i].Content),
+					string(files[i].Content),
+					example.path+" "+expected[i].Name,
+				)
+			}
+		}
+	})
+
+	t.Run("helpers", func(t *testing.T) {
+		t.Parallel()
+
+		f := NewTemplate(TemplateOptions{
+			Text: "{{ .Index }} {{ .Server.Metadata.City }}\n" +
+				"{{ ipv4 .Configuration.InterfaceAddresses | join \",\" }}\n" +
+				"{{ ipv6 .Configuration.InterfaceAddresses | join \",\" }}\n" +
+				"{{ ipv4 .Configuration.DNS | join \",\" }}\n" +
+				"{{ publicKey .Configuration.PrivateKey }}\n" +
+				"{{ hexKey (index .Configuration.Peers 0).PublicKey }}\n",
+		})
+
+		files, err := f.FormatBatch(testItems())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		assert.Equal(t, "mullvad_1.txt", files[1].Name)
+		assert.Equal(
+			t,
+			"1 Gothenburg\n"+
+				"10.64.0.2/32\n"+
+				"fc00:bbbb:bbbb:bb01::2/128\n"+
+				"10.64.0.1\n"+
+				"7XoA1vazTjkrigtmph2+U8ywMzFmh6UQlyZMxdN/yko=\n"+
+				"dd09d263a39b664f0a9c3ac73724f81f"+
+				"77417fbb0d7c5331f189766295e0d56d\n",
+			string(files[1].Content),
+		)
+	})
+
+	t.Run("errors name the configuration", func(t *testing.T) {
+		t.Parallel()
+
+		item := testItems()[0]
+		item.Configuration.PrivateKey = "private_key"
+
+		_, err := NewTemplate(TemplateOptions{
+			Text: "{{ publicKey .Configuration.PrivateKey }}",
+		}).FormatBatch([]Item{item})
+		assert.ErrorContains(t, err, "format nordvpn_0 as template")
+		assert.ErrorContains(t, err, "private key is not valid base64")
+	})
+
+	t.Run("a template is required", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := NewTemplate(TemplateOptions{}).FormatBatch(testItems())
+		assert.EqualError(t, err, "no template given")
+	})
+}
+
+func TestTemplateFamily(t *testing.T) {
+	t.Parallel()
+
+	_, err := templateFamily(true)("10.0.0.1")
+	assert.EqualError(t, err, "cannot split string by address family")
+
+	v6, err := templateFamily(false)([]netip.Addr{
+		netip.MustParseAddr("10.0.0.1"),
+		netip.MustParseAddr("fd00::1"),
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []netip.Addr{netip.MustParseAddr("fd00::1")}, v6)
+}
@@ -0,0 +1,167 @@
+package format
+
+import (
+	"fmt"
+	"strings"
+)
+
+// vyosPeerNameMaxLen keeps peer names short enough to be shown in full by
+// show interfaces wireguard.
+const vyosPeerNameMaxLen = 32
+
+// VyOSOptions tunes the VyOS output.
+type VyOSOptions struct {
+	// RouteTable adds static routes for the peers' AllowedIPs through the
+	// tunnel to this routing table, ready to be used by policy routing.
+	// Zero adds no routes, so a default route cannot take over the router.
+	RouteTable uint32
+}
+
+// VyOS renders configurations as VyOS configuration mode commands that can
+// be pasted into configure or run with vbash.
+type VyOS struct {
+	options VyOSOptions
+}
+
+// NewVyOS initializes and returns a VyOS formatter.
+func NewVyOS(options VyOSOptions) *VyOS {
+	return &VyOS{options: options}
+}
+
+func (f *VyOS) Name() string { return "vyos" }
+
+func (f *VyOS) Extension() string { return ".vyos" }
+
+// Format renders the commands of a single configuration on interface wg0.
+func (f *VyOS) Format(item Item) ([]byte, error) {
+	if err := checkEndpointAddrs(item.Configuration); err != nil {
+		return nil, err
+	}
+	return f.render(item, 0), nil
+}
+
+// FormatBatch writes one command set per configuration. VyOS only accepts
+// wgN names, so the interfaces are numbered by identity, which keeps the
+// interface of every server when the server list is reordered.
+func (f *VyOS) FormatBatch(items []Item) ([]File, error) {
+	if err := checkItemEndpointAddrs(f, items); err != nil {
+		return nil, err
+	}
+
+	files := make([]File, 0, len(items))
+
+	for i, item := range sortedByIdentity(items) {
+		files = append(files, File{
+			Name:    item.Name + f.Extension(),
+			Content: f.render(item, i),
+		})
+	}
+
+	return files, nil
+}
+
+func (f *VyOS) render(item Item, instance int) []byte {
+	config := item.Configuration
+	name := fmt.Sprintf("wg%d", instance)
+	prefix := "set interfaces wireguard " + name
+	var sb strings.Builder
+
+	for _, address := range config.InterfaceAddresses {
+		fmt.Fprintf(
+			&sb,
+			"%s address %s\n",
+			prefix,
+			vyosQuote(address.String()),
+		)
+	}
+	fmt.Fprintf(&sb, "%s description %s\n", prefix, vyosQuote(item.Name))
+	fmt.Fprintf(
+		&sb,
+		"%s private-key %s\n",
+		prefix,
+		vyosQuote(config.PrivateKey),
+	)
+	if config.ListenPort > 0 {
+		fmt.Fprintf(&sb, "%s port %d\n", prefix, config.ListenPort)
+	}
+	if config.MTU > 0 {
+		fmt.Fprintf(&sb, "%s mtu %d\n", prefix, config.MTU)
+	}
+
+	for i, peer := range config.Peers {
+		peerPrefix := fmt.Sprintf(
+			"%s peer %s",
+			prefix,
+			interfaceName(firewallPeerName(item, i), vyosPeerNameMaxLen),
+		)
+
+		fmt.Fprintf(
+			&sb,
+			"%s public-key %s\n",
+			peerPrefix,
+			vyosQuote(peer.PublicKey),
+		)
+		if peer.PresharedKey != "" {
+			fmt.Fprintf(
+				&sb,
+				"%s preshared-key %s\n",
+				peerPrefix,
+				vyosQuote(peer.PresharedKey),
+			)
+		}
+		for _, allowedIP := range peer.AllowedIPs {
+			fmt.Fprintf(
+				&sb,
+				"%s allowed-ips %s\n",
+				peerPrefix,
+				vyosQuote(allowedIP.String()),
+			)
+		}
+		if peer.Endpoint.IsValid() {
+			fmt.Fprintf(
+				&sb,
+				"%s address %s\n",
+				peerPrefix,
+				vyosQuote(peer.Endpoint.Addr().String()),
+			)
+			fmt.Fprintf(&sb, "%s port %d\n", peerPrefix, peer.Endpoint.Port())
+		}
+		if peer.PersistentKeepalive > 0 {
+			fmt.Fprintf(
+				&sb,
+				"%s persistent-keepalive %d\n",
+				peerPrefix,
+				peer.PersistentKeepalive,
+			)
+		}
+	}
+
+	if f.options.RouteTable > 0 {
+		for _, peer := range config.Peers {
+			for _, route := range peer.AllowedIPs {
+				protocol := "route"
+				if route.Addr().Is6() {
+					protocol = "route6"
+				}
+
+				fmt.Fprintf(
+					&sb,
+					"set protocols static table %d %s %s interface %s\n",
+					f.options.RouteTable,
+					protocol,
+					route,
+					name,
+				)
+			}
+		}
+	}
+
+	return []byte(sb.String())
+}
+
+// vyosQuote single quotes a value for the vbash shell configuration mode
+// runs in. Single quotes inside the value close the quotes, are escaped and
+// reopen them.
+func vyosQuote(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+}
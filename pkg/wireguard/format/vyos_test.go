@@ -0,0 +1,42 @@
+package format
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVyOS(t *testing.T) {
+	t.Parallel()
+
+	content, err := NewVyOS(VyOSOptions{RouteTable: 100}).
+		Format(testItems()[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Contains(
+		t,
+		string(content),
+		"set protocols static table 100 route 0.0.0.0/0 interface wg0\n"+
+			"set protocols static table 100 route6 ::/0 interface wg0\n",
+	)
+	assert.Equal(t, `'it'\''s'`, vyosQuote("it's"))
+
+	items := testItems()
+	reordered := slices.Clone(items)
+	slices.Reverse(reordered)
+
+	batch, err := NewVyOS(VyOSOptions{}).FormatBatch(items)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reorderedBatch, err := NewVyOS(VyOSOptions{}).FormatBatch(reordered)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Interfaces are numbered by server, not by position in the list.
+	assert.ElementsMatch(t, batch, reorderedBatch)
+}
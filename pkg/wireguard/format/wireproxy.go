@@ -0,0 +1,231 @@
+package format
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net/netip"
+	"strconv"
+	"strings"
+)
+
+// DefaultWireproxySocksPort is the conventional SOCKS5 port, which the first
+// configuration listens on unless told otherwise.
+const DefaultWireproxySocksPort = 1080
+
+// ErrNoWireproxyListeners is returned when the options leave a wireproxy
+// config without a single listener, which would make it useless.
+var ErrNoWireproxyListeners = errors.New(
+	"wireproxy needs a SOCKS5 port, an HTTP port or a tunnel",
+)
+
+// WireproxyTunnel forwards a local TCP port through the tunnel to Target.
+type WireproxyTunnel struct {
+	Port   uint16
+	Target string
+}
+
+// ParseWireproxyTunnel parses a tunnel written as port=host:port.
+func ParseWireproxyTunnel(value string) (WireproxyTunnel, error) {
+	port, target, ok := strings.Cut(value, "=")
+	if !ok || target == "" {
+		return WireproxyTunnel{}, fmt.Errorf(
+			"invalid wireproxy tunnel %q: expected port=host:port",
+			value,
+		)
+	}
+
+	parsed, err := strconv.ParseUint(port, 10, 16)
+	if err != nil || parsed == 0 {
+		return WireproxyTunnel{}, fmt.Errorf(
+			"invalid wireproxy tunnel %q: invalid port %q",
+			value,
+			port,
+		)
+	}
+
+	return WireproxyTunnel{Port: uint16(parsed), Target: target}, nil
+}
+
+// WireproxyOptions tunes the wireproxy output. Every port is the one used by
+// the first configuration; the configurations after it count up from there,
+// so a whole server list can run side by side.
+type WireproxyOptions struct {
+	// BindAddress is the local address all listeners bind to. It defaults
+	// to the IPv4 loopback address, keeping the proxies private to the host.
+	BindAddress netip.Addr
+	// SocksPort adds a [Socks5] listener when not zero.
+	SocksPort uint16
+	// HTTPPort adds an [http] proxy listener when not zero.
+	HTTPPort uint16
+	// Tunnels adds a [TCPClientTunnel] per entry.
+	Tunnels []WireproxyTunnel
+}
+
+// Wireproxy renders configurations as wireproxy configs, which expose a
+// WireGuard peer as local SOCKS5 and HTTP proxies from userspace.
+type Wireproxy struct {
+	options WireproxyOptions
+}
+
+// NewWireproxy initializes and returns a Wireproxy formatter. Options
+// without any listener make it fail with ErrNoWireproxyListeners.
+func NewWireproxy(options WireproxyOptions) *Wireproxy {
+	if !options.BindAddress.IsValid() {
+		options.BindAddress = netip.AddrFrom4([4]byte{127, 0, 0, 1})
+	}
+	return &Wireproxy{options: options}
+}
+
+func (f *Wireproxy) Name() string { return "wireproxy" }
+
+func (f *Wireproxy) Extension() string { return ".wireproxy.conf" }
+
+// Format renders the config of a single configuration with the listeners on
+// their base ports.
+func (f *Wireproxy) Format(item Item) ([]byte, error) {
+	if len(f.listeners()) == 0 {
+		return nil, ErrNoWireproxyListeners
+	}
+	return f.render(item, 0)
+}
+
+// FormatBatch writes one config per configuration, offsetting every
+// listener port by the configuration's position. Listeners of different
+// configurations that would end up on the same port are an error.
+func (f *Wireproxy) FormatBatch(items []Item) ([]File, error) {
+	if len(f.listeners()) == 0 {
+		return nil, ErrNoWireproxyListeners
+	}
+
+	owners := map[int]string{}
+	files := make([]File, 0, len(items))
+
+	for i, item := range items {
+		for _, listener := range f.listeners() {
+			port := int(listener.port) + i
+			if owner, ok := owners[port]; ok {
+				return nil, fmt.Errorf(
+					"wireproxy port %d of %s is already used by %s",
+					port,
+					item.Name,
+					owner,
+				)
+			}
+			owners[port] = item.Name
+		}
+
+		content, err := f.render(item, i)
+		if err != nil {
+			return nil, fmt.Errorf(
+				"format %s as %s: %w",
+				item.Name,
+				f.Name(),
+				err,
+			)
+		}
+
+		files = append(files, File{
+			Name:    item.Name + f.Extension(),
+			Content: content,
+		})
+	}
+
+	return files, nil
+}
+
+type wireproxyListener struct {
+	section string
+	port    uint16
+	target  string
+}
+
+func (f *Wireproxy) listeners() []wireproxyListener {
+	var listeners []wireproxyListener
+
+	for _, tunnel := range f.options.Tunnels {
+		listeners = append(listeners, wireproxyListener{
+			section: "TCPClientTunnel",
+			port:    tunnel.Port,
+			target:  tunnel.Target,
+		})
+	}
+	if f.options.SocksPort > 0 {
+		listeners = append(listeners, wireproxyListener{
+			section: "Socks5",
+			port:    f.options.SocksPort,
+		})
+	}
+	if f.options.HTTPPort > 0 {
+		listeners = append(listeners, wireproxyListener{
+			section: "http",
+			port:    f.options.HTTPPort,
+		})
+	}
+
+	return listeners
+}
+
+func (f *Wireproxy) render(item Item, offset int) ([]byte, error) {
+	config := item.Configuration
+	var sb strings.Builder
+
+	fmt.Fprintf(&sb, "[Interface]\n")
+	fmt.Fprintf(
+		&sb,
+		"Address = %s\n",
+		joinStrings(config.InterfaceAddresses, ", "),
+	)
+	fmt.Fprintf(&sb, "PrivateKey = %s\n", config.PrivateKey)
+	if len(config.DNS) > 0 {
+		// wireproxy resolves names through the tunnel itself and has no use
+		// for search domains.
+		fmt.Fprintf(&sb, "DNS = %s\n", joinStrings(config.DNS, ", "))
+	}
+	if config.ListenPort > 0 {
+		fmt.Fprintf(&sb, "ListenPort = %d\n", config.ListenPort)
+	}
+	if config.MTU > 0 {
+		fmt.Fprintf(&sb, "MTU = %d\n", config.MTU)
+	}
+
+	for _, peer := range config.Peers {
+		fmt.Fprintf(&sb, "\n[Peer]\n")
+		fmt.Fprintf(&sb, "PublicKey = %s\n", peer.PublicKey)
+		if peer.PresharedKey != "" {
+			fmt.Fprintf(&sb, "PresharedKey = %s\n", peer.PresharedKey)
+		}
+		if endpoint := peer.EndpointString(); endpoint != "" {
+			fmt.Fprintf(&sb, "Endpoint = %s\n", endpoint)
+		}
+		fmt.Fprintf(
+			&sb,
+			"AllowedIPs = %s\n",
+			joinStrings(peer.AllowedIPs, ", "),
+		)
+		if peer.PersistentKeepalive > 0 {
+			fmt.Fprintf(
+				&sb,
+				"PersistentKeepalive = %d\n",
+				peer.PersistentKeepalive,
+			)
+		}
+	}
+
+	for _, listener := range f.listeners() {
+		port := int(listener.port) + offset
+		if port > math.MaxUint16 {
+			return nil, fmt.Errorf("wireproxy port %d is out of range", port)
+		}
+
+		bind := netip.AddrPortFrom(f.options.BindAddress, uint16(port))
+
+		fmt.Fprintf(&sb, "\n[%s]\n", listener.section)
+		fmt.Fprintf(&sb, "BindAddress = %s\n", bind)
+		if listener.target != "" {
+			fmt.Fprintf(&sb, "Target = %s\n", listener.target)
+		}
+	}
+
+	return []byte(sb.String()), nil
+}
@@ -0,0 +1,103 @@
+package format
+
+import (
+	"net/netip"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWireproxy(t *testing.T) {
+	t.Parallel()
+
+	t.Run("listener ports count up per config", func(t *testing.T) {
+		t.Parallel()
+
+		files, err := NewWireproxy(WireproxyOptions{
+			BindAddress: netip.MustParseAddr("0.0.0.0"),
+			SocksPort:   1080,
+			HTTPPort:    8080,
+			Tunnels:     []WireproxyTunnel{{Port: 2222, Target: "10.0.0.1:22"}},
+		}).FormatBatch(testItems())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, file := range files {
+			assertGolden(
+				t,
+				filepath.Join(
+					"testdata",
+					"wireproxy",
+					"pool",
+					file.Name+".golden",
+				),
+				file.Content,
+			)
+		}
+	})
+
+	t.Run("overlapping listeners are rejected", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := NewWireproxy(WireproxyOptions{
+			SocksPort: 1080,
+			HTTPPort:  1081,
+		}).FormatBatch(testItems())
+		assert.ErrorContains(
+			t,
+			err,
+			"wireproxy port 1081 of mullvad_1 is already used by nordvpn_0",
+		)
+	})
+
+	t.Run("a socks port of 0 disables the listener", func(t *testing.T) {
+		t.Parallel()
+
+		content, err := NewWireproxy(WireproxyOptions{HTTPPort: 8080}).
+			Format(testItems()[0])
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		assert.NotContains(t, string(content), "[Socks5]")
+		assert.Contains(t, string(content), "[http]")
+	})
+
+	t.Run("configs without listeners are rejected", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := NewWireproxy(WireproxyOptions{SocksPort: 0}).
+			FormatBatch(testItems())
+		assert.ErrorIs(t, err, ErrNoWireproxyListeners)
+	})
+
+	t.Run("ports past the last one are rejected", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := NewWireproxy(WireproxyOptions{SocksPort: 65535}).
+			FormatBatch(testItems())
+		assert.ErrorContains(t, err, "wireproxy port 65536 is out of range")
+	})
+
+	t.Run("tunnels are parsed from port=target", func(t *testing.T) {
+		t.Parallel()
+
+		tunnel, err := ParseWireproxyTunnel("8443=example.com:443")
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(
+			t,
+			WireproxyTunnel{Port: 8443, Target: "example.com:443"},
+			tunnel,
+		)
+
+		_, err = ParseWireproxyTunnel("example.com:443")
+		assert.ErrorContains(t, err, "expected port=host:port")
+
+		_, err = ParseWireproxyTunnel("0=example.com:443")
+		assert.ErrorContains(t, err, `invalid port "0"`)
+	})
+}
@@ -0,0 +1,151 @@
+package format
+
+import (
+	"fmt"
+
+	"github.com/xbnz/wireguard-config-generator/pkg/wireguard"
+)
+
+// Xray renders configurations as Xray-core wireguard outbounds together
+// with a routing balancer spreading traffic over all of them.
+type Xray struct {
+	options ProxyGroupOptions
+}
+
+// NewXray initializes and returns an Xray formatter. Xray balancers have no
+// fallback strategy, so the balancer always uses leastPing, backed by an
+// observatory probing the group URL, which skips unreachable outbounds as
+// well.
+func NewXray(options ProxyGroupOptions) *Xray {
+	return &Xray{options: options.withDefaults()}
+}
+
+func (f *Xray) Name() string { return "xray" }
+
+func (f *Xray) Extension() string { return ".json" }
+
+// Format renders a fragment holding a single outbound and its balancer.
+func (f *Xray) Format(item Item) ([]byte, error) {
+	return f.render([]Item{item})
+}
+
+// FormatBatch writes all outbounds and the balancer into a single
+// xray.json.
+func (f *Xray) FormatBatch(items []Item) ([]File, error) {
+	content, err := f.render(items)
+	if err != nil {
+		return nil, err
+	}
+
+	return []File{{Name: f.Name() + f.Extension(), Content: content}}, nil
+}
+
+type xrayDocument struct {
+	Outbounds   []xrayOutbound  `json:"outbounds"`
+	Routing     xrayRouting     `json:"routing"`
+	Observatory xrayObservatory `json:"observatory"`
+}
+
+type xrayOutbound struct {
+	Protocol string            `json:"protocol"`
+	Tag      string            `json:"tag"`
+	Settings xrayWireGuardSpec `json:"settings"`
+}
+
+type xrayWireGuardSpec struct {
+	SecretKey string     `json:"secretKey"`
+	Address   []string   `json:"address"`
+	Peers     []xrayPeer `json:"peers"`
+	// Reserved is only set by services such as Cloudflare WARP, which
+	// identify clients by it. Zero bytes leave it unused.
+	Reserved []int  `json:"reserved"`
+	MTU      uint16 `json:"mtu,omitempty"`
+}
+
+type xrayPeer struct {
+	PublicKey    string   `json:"publicKey"`
+	PreSharedKey string   `json:"preSharedKey,omitempty"`
+	Endpoint     string   `json:"endpoint"`
+	KeepAlive    uint16   `json:"keepAlive,omitempty"`
+	AllowedIPs   []string `json:"allowedIPs"`
+}
+
+type xrayRouting struct {
+	Balancers []xrayBalancer `json:"balancers"`
+	Rules     []xrayRule     `json:"rules"`
+}
+
+type xrayBalancer struct {
+	Tag      string       `json:"tag"`
+	Selector []string     `json:"selector"`
+	Strategy xrayStrategy `json:"strategy"`
+}
+
+type xrayStrategy struct {
+	Type string `json:"type"`
+}
+
+type xrayRule struct {
+	Type        string `json:"type"`
+	Network     string `json:"network"`
+	BalancerTag string `json:"balancerTag"`
+}
+
+type xrayObservatory struct {
+	SubjectSelector []string `json:"subjectSelector"`
+	ProbeURL        string   `json:"probeUrl"`
+	ProbeInterval   string   `json:"probeInterval"`
+}
+
+func (f *Xray) render(items []Item) ([]byte, error) {
+	tags := proxyTags(items)
+	document := xrayDocument{
+		Outbounds: make([]xrayOutbound, 0, len(items)),
+		Routing: xrayRouting{
+			Balancers: []xrayBalancer{{
+				Tag:      f.options.Name,
+				Selector: tags,
+				Strategy: xrayStrategy{Type: "leastPing"},
+			}},
+			Rules: []xrayRule{{
+				Type:        "field",
+				Network:     "tcp,udp",
+				BalancerTag: f.options.Name,
+			}},
+		},
+		Observatory: xrayObservatory{
+			SubjectSelector: tags,
+			ProbeURL:        f.options.URL,
+			ProbeInterval:   f.options.Interval.String(),
+		},
+	}
+
+	for i, item := range items {
+		config := item.Configuration
+
+		peer, err := proxyPeer("xray", config)
+		if err != nil {
+			return nil, fmt.Errorf("format %s as xray: %w", item.Name, err)
+		}
+
+		document.Outbounds = append(document.Outbounds, xrayOutbound{
+			Protocol: "wireguard",
+			Tag:      tags[i],
+			Settings: xrayWireGuardSpec{
+				SecretKey: config.PrivateKey,
+				Address:   toStrings(config.InterfaceAddresses),
+				Peers: []xrayPeer{{
+					PublicKey:    peer.PublicKey,
+					PreSharedKey: peer.PresharedKey,
+					Endpoint:     peer.Endpoint.String(),
+					KeepAlive:    peer.PersistentKeepalive,
+					AllowedIPs:   toStrings(peer.AllowedIPs),
+				}},
+				Reserved: []int{0, 0, 0},
+				MTU:      config.MTU,
+			},
+		})
+	}
+
+	return wireguard.MarshalJSON(document)
+}
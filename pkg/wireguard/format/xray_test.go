@@ -0,0 +1,38 @@
+package format
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestXray(t *testing.T) {
+	t.Parallel()
+
+	content, err := NewXray(ProxyGroupOptions{Name: "vpn"}).
+		Format(testItems()[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var document xrayDocument
+	if err := json.NewDecoder(bytes.NewReader(content)).
+		Decode(&document); err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, "vpn", document.Routing.Balancers[0].Tag)
+	assert.Equal(t, "vpn", document.Routing.Rules[0].BalancerTag)
+	assert.Equal(
+		t,
+		[]string{"SE Gothenburg se-got-wg-001"},
+		document.Routing.Balancers[0].Selector,
+	)
+	assert.Equal(
+		t,
+		"[2a03:1b20:3:f011::a01f]:51820",
+		document.Outbounds[0].Settings.Peers[0].Endpoint,
+	)
+}
@@ -5,12 +5,22 @@ import (
 	"net/netip"
 )
 
+// ServerConfiguration is a generated configuration together with the server
+// its peer points at.
+type ServerConfiguration struct {
+	Configuration
+	Server Server
+}
+
+// ConfigGenerator generates a configuration for every server of a provider.
 type ConfigGenerator interface {
+	// List returns the configurations together with the servers they point
+	// at.
 	List(
 		ctx context.Context,
 		interfaceAddresses []netip.Prefix,
 		allowedIPs []netip.Prefix,
 		persistentKeepalive uint16,
 		dns []netip.Addr,
-	) ([]Configuration, error)
+	) ([]ServerConfiguration, error)
 }
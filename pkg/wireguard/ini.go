@@ -0,0 +1,367 @@
+package wireguard
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/netip"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// INILine is a line of a wg-quick section that has no dedicated field on
+// Configuration or PeerConfig, such as a comment, a hook like PostUp or a key
+// this package does not know about. A line with an empty Key is a comment.
+type INILine struct {
+	Key     string `json:"key,omitempty"     yaml:"key,omitempty"`
+	Value   string `json:"value,omitempty"   yaml:"value,omitempty"`
+	Comment string `json:"comment,omitempty" yaml:"comment,omitempty"`
+}
+
+// ParseError reports a malformed line in a wg-quick configuration file.
+type ParseError struct {
+	Line int
+	Err  error
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("line %d: %v", e.Line, e.Err)
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// ParseINI reads a configuration in the wg-quick INI format. Section and key
+// names are matched case-insensitively, repeated list keys such as Address
+// are merged, and comments and unknown keys are kept in Extra, so writing the
+// result with ToINIFormat keeps every setting and comment. The layout is not
+// kept: known keys are written first, in a fixed order, followed by the
+// section's Extra, which holds the comments of known keys too. An Endpoint
+// naming a host rather than an address is read into EndpointHost, and
+// AmneziaWG's obfuscation keys are read into AmneziaWG.
+func ParseINI(r io.Reader) (Configuration, error) {
+	var (
+		config       Configuration
+		section      string
+		hasInterface bool
+		seen         map[string]bool
+		preamble     []INILine
+	)
+
+	scanner := bufio.NewScanner(r)
+	lineNumber := 0
+
+	for scanner.Scan() {
+		lineNumber++
+
+		content, comment, hasComment := strings.Cut(scanner.Text(), "#")
+		content = strings.TrimSpace(content)
+		comment = strings.TrimRightFunc(comment, unicode.IsSpace)
+
+		if content == "" {
+			if !hasComment {
+				continue
+			}
+
+			if section == "" {
+				preamble = append(preamble, INILine{Comment: comment})
+				continue
+			}
+
+			appendINILines(&config, section, INILine{Comment: comment})
+			continue
+		}
+
+		if strings.HasPrefix(content, "[") && strings.HasSuffix(content, "]") {
+			name := strings.ToLower(
+				strings.TrimSpace(content[1 : len(content)-1]),
+			)
+
+			switch name {
+			case "interface":
+				if hasInterface {
+					return Configuration{}, &ParseError{
+						Line: lineNumber,
+						Err:  errors.New("duplicate [Interface] section"),
+					}
+				}
+				hasInterface = true
+			case "peer":
+				config.Peers = append(config.Peers, PeerConfig{})
+			default:
+				return Configuration{}, &ParseError{
+					Line: lineNumber,
+					Err:  fmt.Errorf("unknown section %q", content),
+				}
+			}
+
+			section = name
+			seen = map[string]bool{}
+			appendINILines(&config, section, preamble...)
+			preamble = nil
+
+			if hasComment {
+				appendINILines(&config, section, INILine{Comment: comment})
+			}
+			continue
+		}
+
+		if section == "" {
+			return Configuration{}, &ParseError{
+				Line: lineNumber,
+				Err:  errors.New("key outside of a section"),
+			}
+		}
+
+		key, value, ok := strings.Cut(content, "=")
+		key = strings.TrimSpace(key)
+		value = strings.TrimSpace(value)
+
+		if !ok || key == "" {
+			return Configuration{}, &ParseError{
+				Line: lineNumber,
+				Err:  fmt.Errorf("expected key = value, got %q", content),
+			}
+		}
+
+		lowerKey := strings.ToLower(key)
+		known, err := parseINIKey(&config, section, lowerKey, value)
+		if err != nil {
+			return Configuration{}, &ParseError{Line: lineNumber, Err: err}
+		}
+
+		switch {
+		case !known:
+			appendINILines(&config, section, INILine{
+				Key:     key,
+				Value:   value,
+				Comment: comment,
+			})
+			continue
+		case seen[lowerKey] && !isINIListKey(lowerKey):
+			return Configuration{}, &ParseError{
+				Line: lineNumber,
+				Err:  fmt.Errorf("duplicate key %q", key),
+			}
+		}
+
+		seen[lowerKey] = true
+
+		if hasComment {
+			appendINILines(&config, section, INILine{Comment: comment})
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return Configuration{}, fmt.Errorf("read configuration: %w", err)
+	}
+
+	if !hasInterface {
+		return Configuration{}, errors.New("missing [Interface] section")
+	}
+
+	return config, nil
+}
+
+func parseINIKey(
+	config *Configuration,
+	section string,
+	key string,
+	value string,
+) (bool, error) {
+	if section == "peer" {
+		return parseINIPeerKey(&config.Peers[len(config.Peers)-1], key, value)
+	}
+
+	var err error
+
+	switch key {
+	case "privatekey":
+		config.PrivateKey = value
+	case "address":
+		var prefixes []netip.Prefix
+		prefixes, err = parseINIPrefixes(value)
+		config.InterfaceAddresses = append(
+			config.InterfaceAddresses,
+			prefixes...,
+		)
+	case "dns":
+		for _, item := range splitINIList(value) {
+			addr, parseErr := netip.ParseAddr(item)
+			if parseErr != nil {
+				config.DNSSearch = append(config.DNSSearch, item)
+				continue
+			}
+			config.DNS = append(config.DNS, addr)
+		}
+	case "listenport":
+		config.ListenPort, err = parseINIUint16(value)
+	case "fwmark":
+		config.FwMark, err = parseINIFwMark(value)
+	case "mtu":
+		config.MTU, err = parseINIUint16(value)
+	default:
+		var params AmneziaWG
+		if config.AmneziaWG != nil {
+			params = *config.AmneziaWG
+		}
+
+		known, err := params.parseKey(key, value)
+		if known && err == nil {
+			config.AmneziaWG = &params
+		}
+		return known, err
+	}
+
+	if err != nil {
+		return true, fmt.Errorf("invalid %s: %w", key, err)
+	}
+
+	return true, nil
+}
+
+func parseINIPeerKey(peer *PeerConfig, key string, value string) (bool, error) {
+	var err error
+
+	switch key {
+	case "publickey":
+		peer.PublicKey = value
+	case "presharedkey":
+		peer.PresharedKey = value
+	case "allowedips":
+		var prefixes []netip.Prefix
+		prefixes, err = parseINIPrefixes(value)
+		peer.AllowedIPs = append(peer.AllowedIPs, prefixes...)
+	case "endpoint":
+		if isHostEndpoint(value) {
+			peer.EndpointHost = value
+			break
+		}
+		peer.Endpoint, err = netip.ParseAddrPort(value)
+	case "persistentkeepalive":
+		if strings.EqualFold(value, "off") {
+			peer.PersistentKeepalive = 0
+			break
+		}
+		peer.PersistentKeepalive, err = parseINIUint16(value)
+	default:
+		return false, nil
+	}
+
+	if err != nil {
+		return true, fmt.Errorf("invalid %s: %w", key, err)
+	}
+
+	return true, nil
+}
+
+// isHostEndpoint reports whether value is a host:port endpoint whose host is
+// a name rather than an IP address.
+func isHostEndpoint(value string) bool {
+	host, port, err := net.SplitHostPort(value)
+	if err != nil || host == "" || strings.Contains(host, ":") {
+		return false
+	}
+	if _, err := strconv.ParseUint(port, 10, 16); err != nil {
+		return false
+	}
+	if _, err := netip.ParseAddr(host); err == nil {
+		return false
+	}
+	return strings.ContainsFunc(host, unicode.IsLetter)
+}
+
+func appendINILines(config *Configuration, section string, lines ...INILine) {
+	if len(lines) == 0 {
+		return
+	}
+
+	if section == "peer" {
+		peer := &config.Peers[len(config.Peers)-1]
+		peer.Extra = append(peer.Extra, lines...)
+		return
+	}
+
+	config.Extra = append(config.Extra, lines...)
+}
+
+func writeINILines(sb *strings.Builder, lines []INILine) {
+	for _, line := range lines {
+		switch {
+		case line.Key == "":
+			fmt.Fprintf(sb, "#%s\n", line.Comment)
+		case line.Comment != "":
+			fmt.Fprintf(sb, "%s = %s #%s\n", line.Key, line.Value, line.Comment)
+		default:
+			fmt.Fprintf(sb, "%s = %s\n", line.Key, line.Value)
+		}
+	}
+}
+
+func isINIListKey(key string) bool {
+	switch key {
+	case "address", "dns", "allowedips":
+		return true
+	default:
+		return false
+	}
+}
+
+func splitINIList(value string) []string {
+	var items []string
+	for item := range strings.SplitSeq(value, ",") {
+		item = strings.TrimSpace(item)
+		if item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func parseINIPrefixes(value string) ([]netip.Prefix, error) {
+	items := splitINIList(value)
+	prefixes := make([]netip.Prefix, 0, len(items))
+
+	for _, item := range items {
+		if !strings.Contains(item, "/") {
+			addr, err := netip.ParseAddr(item)
+			if err != nil {
+				return nil, err
+			}
+			prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+			continue
+		}
+
+		prefix, err := netip.ParsePrefix(item)
+		if err != nil {
+			return nil, err
+		}
+		prefixes = append(prefixes, prefix)
+	}
+
+	return prefixes, nil
+}
+
+func parseINIUint16(value string) (uint16, error) {
+	parsed, err := strconv.ParseUint(value, 10, 16)
+	if err != nil {
+		return 0, err
+	}
+	return uint16(parsed), nil
+}
+
+func parseINIFwMark(value string) (uint32, error) {
+	if strings.EqualFold(value, "off") {
+		return 0, nil
+	}
+
+	parsed, err := strconv.ParseUint(value, 0, 32)
+	if err != nil {
+		return 0, err
+	}
+	return uint32(parsed), nil
+}
@@ -0,0 +1,238 @@
+package wireguard
+
+import (
+	"bytes"
+	"errors"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseINI(t *testing.T) {
+	t.Parallel()
+
+	t.Run("canonical file round trips byte for byte", func(t *testing.T) {
+		t.Parallel()
+
+		content, err := os.ReadFile(filepath.Join("testdata", "canonical.conf"))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		config, err := ParseINI(bytes.NewReader(content))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		ini, err := config.ToINIFormat()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		assert.Equal(t, string(content), ini)
+	})
+
+	t.Run("keys are case insensitive and lists are merged", func(t *testing.T) {
+		t.Parallel()
+
+		file, err := os.Open(filepath.Join("testdata", "loose.conf"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer file.Close()
+
+		config, err := ParseINI(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		assert.Equal(
+			t,
+			"OEvHuuMpALNf7ZZkzUSGbT8vkj89aHrhLyqZlIn4rPU=",
+			config.PrivateKey,
+		)
+		assert.Equal(t, []netip.Prefix{
+			netip.MustParsePrefix("10.5.0.2/32"),
+			netip.MustParsePrefix("fd00::2/128"),
+		}, config.InterfaceAddresses)
+		assert.Equal(t, []netip.Addr{
+			netip.MustParseAddr("103.86.96.100"),
+			netip.MustParseAddr("2001:4860:4860::8888"),
+		}, config.DNS)
+		assert.Equal(t, uint32(0xca6c), config.FwMark)
+		assert.Equal(t, []INILine{
+			{Comment: " exported from a router"},
+			{Comment: " second address"},
+			{Key: "SaveConfig", Value: "true"},
+		}, config.Extra)
+
+		assert.Len(t, config.Peers, 1)
+		assert.Equal(t, []netip.Prefix{
+			netip.MustParsePrefix("0.0.0.0/0"),
+			netip.MustParsePrefix("::/0"),
+		}, config.Peers[0].AllowedIPs)
+		assert.Equal(
+			t,
+			netip.MustParseAddrPort("[2a00:1450::1]:51820"),
+			config.Peers[0].Endpoint,
+		)
+		assert.Equal(t, uint16(0), config.Peers[0].PersistentKeepalive)
+	})
+
+	t.Run("hostname endpoints round trip", func(t *testing.T) {
+		t.Parallel()
+
+		content := strings.Join([]string{
+			"[Interface]",
+			"PrivateKey = OEvHuuMpALNf7ZZkzUSGbT8vkj89aHrhLyqZlIn4rPU=",
+			"Address = 10.5.0.2/32",
+			"DNS = 103.86.96.100",
+			"",
+			"[Peer]",
+			"PublicKey = qIhtTW9K4iXWFo5Q4dOPdXg8/xubXr9yEGoN55D8xnA=",
+			"AllowedIPs = 0.0.0.0/0",
+			"Endpoint = vpn.example:51820",
+			"PersistentKeepalive = 25",
+			"",
+		}, "\n")
+
+		config, err := ParseINI(strings.NewReader(content))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		assert.False(t, config.Peers[0].Endpoint.IsValid())
+		assert.Equal(t, "vpn.example:51820", config.Peers[0].EndpointHost)
+		assert.Empty(t, config.Peers[0].Extra)
+
+		ini, err := config.ToINIFormat()
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, content, ini)
+	})
+
+	t.Run("errors report the line number", func(t *testing.T) {
+		t.Parallel()
+
+		tests := []struct {
+			name      string
+			content   string
+			wantLine  int
+			errSubstr string
+		}{
+			{
+				name:      "key outside of a section",
+				content:   "PrivateKey = abc\n[Interface]\n",
+				wantLine:  1,
+				errSubstr: "key outside of a section",
+			},
+			{
+				name:      "unknown section",
+				content:   "[Interface]\n\n[Socks5]\n",
+				wantLine:  3,
+				errSubstr: "unknown section",
+			},
+			{
+				name:      "missing equals sign",
+				content:   "[Interface]\nPrivateKey\n",
+				wantLine:  2,
+				errSubstr: "expected key = value",
+			},
+			{
+				name:      "invalid address",
+				content:   "[Interface]\nAddress = 10.0.0.300/32\n",
+				wantLine:  2,
+				errSubstr: "invalid address",
+			},
+			{
+				name:      "endpoint without port",
+				content:   "[Interface]\n[Peer]\nEndpoint = 10.0.0.1\n",
+				wantLine:  3,
+				errSubstr: "invalid endpoint",
+			},
+			{
+				name:      "duplicate single valued key",
+				content:   "[Interface]\nMTU = 1420\nmtu = 1280\n",
+				wantLine:  3,
+				errSubstr: "duplicate key",
+			},
+			{
+				name: "duplicate hostname endpoint",
+				content: "[Interface]\n[Peer]\n" +
+					"Endpoint = vpn.example:51820\n" +
+					"Endpoint = 10.0.0.1:51820\n",
+				wantLine:  4,
+				errSubstr: "duplicate key",
+			},
+			{
+				name:      "duplicate interface",
+				content:   "[Interface]\n[Peer]\n[Interface]\n",
+				wantLine:  3,
+				errSubstr: "duplicate [Interface] section",
+			},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				_, err := ParseINI(strings.NewReader(tt.content))
+
+				parseErr, ok := errors.AsType[*ParseError](err)
+				if !ok {
+					t.Fatalf("expected *ParseError, got %v", err)
+				}
+
+				assert.Equal(t, tt.wantLine, parseErr.Line)
+				assert.ErrorContains(t, err, tt.errSubstr)
+			})
+		}
+	})
+
+	t.Run("missing interface section", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := ParseINI(strings.NewReader("# nothing here\n"))
+		assert.ErrorContains(t, err, "missing [Interface] section")
+	})
+}
+
+func FuzzParseINI(f *testing.F) {
+	for _, name := range []string{"canonical.conf", "loose.conf"} {
+		content, err := os.ReadFile(filepath.Join("testdata", name))
+		if err != nil {
+			f.Fatal(err)
+		}
+		f.Add(string(content))
+	}
+	f.Add("[Interface]\nDNS = 1.1.1.1, corp.example\n[Peer]\nFoo = bar #baz\n")
+
+	f.Fuzz(func(t *testing.T, content string) {
+		config, err := ParseINI(strings.NewReader(content))
+		if err != nil {
+			return
+		}
+
+		first, err := config.ToINIFormat()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		reparsed, err := ParseINI(strings.NewReader(first))
+		if err != nil {
+			t.Fatalf("reparse %q: %v", first, err)
+		}
+
+		second, err := reparsed.ToINIFormat()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if first != second {
+			t.Fatalf("round trip mismatch:\n%q\n%q", first, second)
+		}
+	})
+}
@@ -0,0 +1,201 @@
+package wireguard
+
+import (
+	"bufio"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/netip"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DeviceState is the state of a running WireGuard device as reported by the
+// UAPI get operation. Peers holds runtime statistics in the same order as
+// Configuration.Peers.
+type DeviceState struct {
+	Configuration Configuration
+	Peers         []PeerState
+	Errno         int
+}
+
+// PeerState holds the runtime statistics a device reports for a single peer.
+type PeerState struct {
+	PublicKey       string
+	LastHandshake   time.Time
+	RxBytes         uint64
+	TxBytes         uint64
+	ProtocolVersion int
+}
+
+// ParseIPC reads the response to a UAPI get operation. Keys are converted
+// from hex to the base64 form used by Configuration. Unknown keys are ignored
+// so that newer implementations can still be read. A non-zero errno is
+// returned as an error alongside the partially filled state.
+func ParseIPC(r io.Reader) (DeviceState, error) {
+	var (
+		state            DeviceState
+		handshakeSeconds int64
+		handshakeNanos   int64
+	)
+
+	flushHandshake := func() {
+		if len(state.Peers) == 0 {
+			return
+		}
+		if handshakeSeconds != 0 || handshakeNanos != 0 {
+			state.Peers[len(state.Peers)-1].LastHandshake = time.Unix(
+				handshakeSeconds,
+				handshakeNanos,
+			).UTC()
+		}
+		handshakeSeconds, handshakeNanos = 0, 0
+	}
+
+	scanner := bufio.NewScanner(r)
+	lineNumber := 0
+
+	for scanner.Scan() {
+		lineNumber++
+
+		line := scanner.Text()
+		if line == "" {
+			break
+		}
+
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			return DeviceState{}, &ParseError{
+				Line: lineNumber,
+				Err:  fmt.Errorf("expected key=value, got %q", line),
+			}
+		}
+
+		if key == "public_key" {
+			flushHandshake()
+		}
+
+		var err error
+
+		switch key {
+		case "last_handshake_time_sec":
+			handshakeSeconds, err = strconv.ParseInt(value, 10, 64)
+		case "last_handshake_time_nsec":
+			handshakeNanos, err = strconv.ParseInt(value, 10, 64)
+		default:
+			err = parseIPCKey(&state, key, value)
+		}
+
+		if err != nil {
+			return DeviceState{}, &ParseError{
+				Line: lineNumber,
+				Err:  fmt.Errorf("invalid %s: %w", key, err),
+			}
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return DeviceState{}, fmt.Errorf("read device state: %w", err)
+	}
+
+	flushHandshake()
+
+	if state.Errno != 0 {
+		return state, fmt.Errorf("device returned errno %d", state.Errno)
+	}
+
+	return state, nil
+}
+
+func parseIPCKey(state *DeviceState, key string, value string) error {
+	config := &state.Configuration
+
+	if key == "public_key" {
+		publicKey, err := wgHexToKey(value)
+		if err != nil {
+			return err
+		}
+
+		config.Peers = append(config.Peers, PeerConfig{PublicKey: publicKey})
+		state.Peers = append(state.Peers, PeerState{PublicKey: publicKey})
+		return nil
+	}
+
+	var err error
+
+	switch key {
+	case "private_key":
+		config.PrivateKey, err = wgHexToKey(value)
+		return err
+	case "listen_port":
+		config.ListenPort, err = parseINIUint16(value)
+		return err
+	case "fwmark":
+		var fwMark uint64
+		fwMark, err = strconv.ParseUint(value, 10, 32)
+		config.FwMark = uint32(fwMark)
+		return err
+	case "errno":
+		state.Errno, err = strconv.Atoi(value)
+		return err
+	}
+
+	if len(config.Peers) == 0 {
+		return nil
+	}
+
+	return parseIPCPeerKey(
+		&config.Peers[len(config.Peers)-1],
+		&state.Peers[len(state.Peers)-1],
+		key,
+		value,
+	)
+}
+
+func parseIPCPeerKey(
+	peer *PeerConfig,
+	peerState *PeerState,
+	key string,
+	value string,
+) error {
+	var err error
+
+	switch key {
+	case "preshared_key":
+		if strings.Trim(value, "0") != "" {
+			peer.PresharedKey, err = wgHexToKey(value)
+		}
+	case "endpoint":
+		peer.Endpoint, err = netip.ParseAddrPort(value)
+	case "allowed_ip":
+		var prefix netip.Prefix
+		prefix, err = netip.ParsePrefix(value)
+		peer.AllowedIPs = append(peer.AllowedIPs, prefix)
+	case "persistent_keepalive_interval":
+		peer.PersistentKeepalive, err = parseINIUint16(value)
+	case "rx_bytes":
+		peerState.RxBytes, err = strconv.ParseUint(value, 10, 64)
+	case "tx_bytes":
+		peerState.TxBytes, err = strconv.ParseUint(value, 10, 64)
+	case "protocol_version":
+		peerState.ProtocolVersion, err = strconv.Atoi(value)
+	}
+
+	return err
+}
+
+func wgHexToKey(key string) (string, error) {
+	decoded, err := hex.DecodeString(key)
+	if err != nil {
+		return "", fmt.Errorf("parse key: %w", err)
+	}
+	if len(decoded) != 32 {
+		return "", fmt.Errorf(
+			"parse key: invalid key length %d (expected 32)",
+			len(decoded),
+		)
+	}
+	return base64.StdEncoding.EncodeToString(decoded), nil
+}
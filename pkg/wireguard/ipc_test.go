@@ -0,0 +1,156 @@
+package wireguard
+
+import (
+	"errors"
+	"net/netip"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const getResponse = `private_key=384bc7bae32900b35fed9664cd44866d3f2f923f3d687ae12f2a999489f8acf5
+listen_port=51820
+fwmark=51820
+public_key=a8886d4d6f4ae225d6168e50e1d38f75783cff1b9b5ebf72106a0de790fcc670
+preshared_key=0000000000000000000000000000000000000000000000000000000000000000
+protocol_version=1
+endpoint=62.3.36.228:51820
+last_handshake_time_sec=1771806559
+last_handshake_time_nsec=250000000
+tx_bytes=1024
+rx_bytes=4096
+persistent_keepalive_interval=25
+allowed_ip=0.0.0.0/0
+allowed_ip=::/0
+errno=0
+
+`
+
+func TestParseIPC(t *testing.T) {
+	t.Parallel()
+
+	t.Run("it parses a get response", func(t *testing.T) {
+		t.Parallel()
+
+		state, err := ParseIPC(strings.NewReader(getResponse))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		config := state.Configuration
+		assert.Equal(
+			t,
+			"OEvHuuMpALNf7ZZkzUSGbT8vkj89aHrhLyqZlIn4rPU=",
+			config.PrivateKey,
+		)
+		assert.Equal(t, uint16(51820), config.ListenPort)
+		assert.Equal(t, uint32(51820), config.FwMark)
+
+		assert.Len(t, config.Peers, 1)
+		peer := config.Peers[0]
+		assert.Equal(
+			t,
+			"qIhtTW9K4iXWFo5Q4dOPdXg8/xubXr9yEGoN55D8xnA=",
+			peer.PublicKey,
+		)
+		assert.Empty(t, peer.PresharedKey)
+		assert.Equal(
+			t,
+			netip.MustParseAddrPort("62.3.36.228:51820"),
+			peer.Endpoint,
+		)
+		assert.Equal(t, []netip.Prefix{
+			netip.MustParsePrefix("0.0.0.0/0"),
+			netip.MustParsePrefix("::/0"),
+		}, peer.AllowedIPs)
+		assert.Equal(t, uint16(25), peer.PersistentKeepalive)
+
+		assert.Len(t, state.Peers, 1)
+		assert.Equal(t, PeerState{
+			PublicKey:       peer.PublicKey,
+			LastHandshake:   time.Unix(1771806559, 250000000).UTC(),
+			RxBytes:         4096,
+			TxBytes:         1024,
+			ProtocolVersion: 1,
+		}, state.Peers[0])
+	})
+
+	t.Run("it reads what ToIPCFormat writes", func(t *testing.T) {
+		t.Parallel()
+
+		config := NewConfiguration(
+			"OEvHuuMpALNf7ZZkzUSGbT8vkj89aHrhLyqZlIn4rPU=",
+			nil,
+			nil,
+			[]PeerConfig{
+				NewPeerConfig(
+					"qIhtTW9K4iXWFo5Q4dOPdXg8/xubXr9yEGoN55D8xnA=",
+					netip.MustParseAddrPort("62.3.36.228:51820"),
+					[]netip.Prefix{netip.MustParsePrefix("0.0.0.0/0")},
+					25,
+				),
+				NewPeerConfig(
+					"3QnSY6ObZk8KnDrHNyT4H3dBf7sNfFMx8Yl2YpXg1W0=",
+					netip.MustParseAddrPort("[2a00:1450::1]:51820"),
+					[]netip.Prefix{netip.MustParsePrefix("::/0")},
+					0,
+				),
+			},
+		)
+
+		ipc, err := config.ToIPCFormat()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		state, err := ParseIPC(strings.NewReader(ipc))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		assert.Equal(t, config.PrivateKey, state.Configuration.PrivateKey)
+		assert.Equal(t, config.Peers, state.Configuration.Peers)
+	})
+
+	t.Run("ToIPCFormat rejects host endpoints", func(t *testing.T) {
+		t.Parallel()
+
+		config := NewConfiguration(
+			"OEvHuuMpALNf7ZZkzUSGbT8vkj89aHrhLyqZlIn4rPU=",
+			nil,
+			nil,
+			[]PeerConfig{{
+				PublicKey:    "qIhtTW9K4iXWFo5Q4dOPdXg8/xubXr9yEGoN55D8xnA=",
+				EndpointHost: "vpn.example:51820",
+			}},
+		)
+
+		_, err := config.ToIPCFormat()
+		assert.ErrorContains(t, err, "vpn.example:51820 of peer 0 is a host")
+	})
+
+	t.Run("non-zero errno is an error", func(t *testing.T) {
+		t.Parallel()
+
+		state, err := ParseIPC(strings.NewReader("errno=2\n\n"))
+		assert.ErrorContains(t, err, "errno 2")
+		assert.Equal(t, 2, state.Errno)
+	})
+
+	t.Run("malformed lines report the line number", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := ParseIPC(
+			strings.NewReader("listen_port=51820\npublic_key=zz\n\n"),
+		)
+
+		parseErr, ok := errors.AsType[*ParseError](err)
+		if !ok {
+			t.Fatalf("expected *ParseError, got %v", err)
+		}
+		assert.Equal(t, 2, parseErr.Line)
+		assert.ErrorContains(t, err, "invalid public_key")
+	})
+}
@@ -0,0 +1,30 @@
+package wireguard
+
+import (
+	"crypto/ecdh"
+	"encoding/base64"
+	"fmt"
+)
+
+// PublicKey derives the base64 encoded public key belonging to a base64
+// encoded private key.
+func PublicKey(privateKey string) (string, error) {
+	if err := checkKey(privateKey); err != nil {
+		return "", fmt.Errorf("private key %w", err)
+	}
+
+	decoded, _ := base64.StdEncoding.DecodeString(privateKey)
+
+	key, err := ecdh.X25519().NewPrivateKey(decoded)
+	if err != nil {
+		return "", fmt.Errorf("private key: %w", err)
+	}
+
+	return base64.StdEncoding.EncodeToString(key.PublicKey().Bytes()), nil
+}
+
+// HexKey converts a base64 encoded key into the hex encoding used by the
+// UAPI.
+func HexKey(key string) (string, error) {
+	return wgKeyToHex(key)
+}
@@ -0,0 +1,33 @@
+package wireguard
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPublicKey(t *testing.T) {
+	t.Parallel()
+
+	t.Run("it derives the public key", func(t *testing.T) {
+		t.Parallel()
+
+		publicKey, err := PublicKey(
+			"OEvHuuMpALNf7ZZkzUSGbT8vkj89aHrhLyqZlIn4rPU=",
+		)
+
+		assert.NoError(t, err)
+		assert.Equal(
+			t,
+			"7XoA1vazTjkrigtmph2+U8ywMzFmh6UQlyZMxdN/yko=",
+			publicKey,
+		)
+	})
+
+	t.Run("malformed keys are rejected", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := PublicKey("private_key")
+		assert.ErrorContains(t, err, "private key is not valid base64")
+	})
+}
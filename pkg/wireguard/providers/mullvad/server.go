@@ -49,9 +49,13 @@ func (s *Server) List(ctx context.Context) (servers []wireguard.Server, err erro
 	}()
 
 	type Server struct {
-		IPv4   string `json:"ipv4_addr_in" validate:"required_without=IPv6"`
-		IPv6   string `json:"ipv6_addr_in" validate:"required_without=IPv4"`
-		PubKey string `json:"pubkey"`
+		IPv4        string `json:"ipv4_addr_in" validate:"required_without=IPv6"`
+		IPv6        string `json:"ipv6_addr_in" validate:"required_without=IPv4"`
+		PubKey      string `json:"pubkey"`
+		Hostname    string `json:"hostname"`
+		CountryName string `json:"country_name"`
+		CountryCode string `json:"country_code"`
+		CityName    string `json:"city_name"`
 	}
 
 	request, err := http.NewRequestWithContext(
@@ -124,11 +128,19 @@ func (s *Server) List(ctx context.Context) (servers []wireguard.Server, err erro
 				}
 			}
 
-			return wireguard.NewServer(
+			server := wireguard.NewServer(
 				s.PubKey,
 				netip.AddrPortFrom(addr, mullvadDefaultWireguardPort),
 				netip.AddrPortFrom(addr6, mullvadDefaultWireguardPort),
 			)
+			server.Metadata = wireguard.ServerMetadata{
+				Hostname:    s.Hostname,
+				Country:     s.CountryName,
+				CountryCode: s.CountryCode,
+				City:        s.CityName,
+			}
+
+			return server
 		},
 	)
 
@@ -37,7 +37,7 @@ func (c *ConfigGenerator) List(
 	allowedIPs []netip.Prefix,
 	persistentKeepalive uint16,
 	dns []netip.Addr,
-) ([]wireguard.Configuration, error) {
+) ([]wireguard.ServerConfiguration, error) {
 	pk, err := c.privateKeyFetcher.Fetch(ctx)
 	if err != nil {
 		return nil, fmt.Errorf(
@@ -54,19 +54,25 @@ func (c *ConfigGenerator) List(
 		)
 	}
 
-	return lo.Map(servers, func(ns wireguard.Server, _ int) wireguard.Configuration {
-		peer := wireguard.NewPeerConfig(
-			ns.PublicKey,
-			ns.Endpoint,
-			allowedIPs,
-			persistentKeepalive,
-		)
+	return lo.Map(
+		servers,
+		func(ns wireguard.Server, _ int) wireguard.ServerConfiguration {
+			peer := wireguard.NewPeerConfig(
+				ns.PublicKey,
+				ns.Endpoint,
+				allowedIPs,
+				persistentKeepalive,
+			)
 
-		return wireguard.NewConfiguration(
-			string(pk),
-			interfaceAddresses,
-			dns,
-			[]wireguard.PeerConfig{peer},
-		)
-	}), nil
+			return wireguard.ServerConfiguration{
+				Configuration: wireguard.NewConfiguration(
+					string(pk),
+					interfaceAddresses,
+					dns,
+					[]wireguard.PeerConfig{peer},
+				),
+				Server: ns,
+			}
+		},
+	), nil
 }
@@ -11,6 +11,8 @@ import (
 
 	"github.com/go-playground/validator/v10"
 	"github.com/stretchr/testify/assert"
+
+	"github.com/xbnz/wireguard-config-generator/pkg/wireguard"
 )
 
 func TestConfigGenerator_(t *testing.T) {
@@ -31,7 +33,7 @@ func TestConfigGenerator_(t *testing.T) {
 				rw.Write(
 					[]byte(
 						fmt.Sprintf(
-							`[{"station":"62.3.36.228","technologies":[{"identifier":"wireguard_udp","metadata":[{"name":"public_key","value":"%s"}]}]}]`,
+							`[{"station":"62.3.36.228","hostname":"de1000.nordvpn.com","load":17,"locations":[{"country":{"name":"Germany","code":"DE","city":{"name":"Frankfurt"}}}],"technologies":[{"identifier":"wireguard_udp","metadata":[{"name":"public_key","value":"%s"}]}]}]`,
 							expectedPublicKey,
 						),
 					),
@@ -126,6 +128,17 @@ func TestConfigGenerator_(t *testing.T) {
 					config.Peers[0].PersistentKeepalive,
 				)
 				assert.True(t, slices.Equal(tt.dns, config.DNS))
+				assert.Equal(
+					t,
+					wireguard.ServerMetadata{
+						Hostname:    "de1000.nordvpn.com",
+						Country:     "Germany",
+						CountryCode: "DE",
+						City:        "Frankfurt",
+						Load:        17,
+					},
+					config.Server.Metadata,
+				)
 				assert.True(
 					t,
 					slices.Equal(
@@ -61,8 +61,25 @@ func (s *Server) List(ctx context.Context) (servers []wireguard.Server, err erro
 		Metadata   []Metadata `json:"metadata"   validate:"omitempty,dive"`
 	}
 
+	type City struct {
+		Name string `json:"name"`
+	}
+
+	type Country struct {
+		Name string `json:"name"`
+		Code string `json:"code"`
+		City City   `json:"city"`
+	}
+
+	type Location struct {
+		Country Country `json:"country"`
+	}
+
 	type Server struct {
 		IPAddress    string       `json:"station"      validate:"required,ip"`
+		Hostname     string       `json:"hostname"`
+		Load         int          `json:"load"`
+		Locations    []Location   `json:"locations"`
 		Technologies []Technology `json:"technologies" validate:"required,dive"`
 	}
 
@@ -169,11 +186,23 @@ func (s *Server) List(ctx context.Context) (servers []wireguard.Server, err erro
 				panic("invalid ip address")
 			}
 
-			return wireguard.NewServer(
+			server := wireguard.NewServer(
 				publicKeyMeta.Value,
 				netip.AddrPortFrom(addr, nordVpnDefaultWireguardPort),
 				netip.AddrPort{},
 			)
+			server.Metadata = wireguard.ServerMetadata{
+				Hostname: s.Hostname,
+				Load:     s.Load,
+			}
+
+			if location, ok := lo.First(s.Locations); ok {
+				server.Metadata.Country = location.Country.Name
+				server.Metadata.CountryCode = location.Country.Code
+				server.Metadata.City = location.Country.City.Name
+			}
+
+			return server
 		},
 	)
 
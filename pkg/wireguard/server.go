@@ -11,6 +11,18 @@ type Server struct {
 	PublicKey  string
 	Endpoint   netip.AddrPort
 	EndpointV6 netip.AddrPort
+	Metadata   ServerMetadata
+}
+
+// ServerMetadata describes where a server is and how busy it is, as far as
+// the provider reports it. Unknown values are left empty.
+type ServerMetadata struct {
+	Hostname    string `json:"hostname,omitempty"     yaml:"hostname,omitempty"`
+	Country     string `json:"country,omitempty"      yaml:"country,omitempty"`
+	CountryCode string `json:"country_code,omitempty" yaml:"country_code,omitempty"`
+	City        string `json:"city,omitempty"         yaml:"city,omitempty"`
+	// Load is the server's load in percent.
+	Load int `json:"load,omitempty" yaml:"load,omitempty"`
 }
 
 func NewServer(publicKey string, endpoint netip.AddrPort, endpointV6 netip.AddrPort) Server {
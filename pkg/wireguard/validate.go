@@ -0,0 +1,328 @@
+package wireguard
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/netip"
+)
+
+const (
+	keyLength = 32
+
+	// Keepalives below this only add traffic, and above this most NAT
+	// mappings have already expired by the time the next one is sent.
+	minRecommendedKeepalive = 10
+	maxRecommendedKeepalive = 120
+)
+
+// Severity grades a Finding. Only SeverityError findings make a
+// configuration unusable.
+type Severity int
+
+const (
+	SeverityInfo Severity = iota
+	SeverityWarning
+	SeverityError
+)
+
+func (s Severity) String() string {
+	switch s {
+	case SeverityInfo:
+		return "info"
+	case SeverityWarning:
+		return "warning"
+	case SeverityError:
+		return "error"
+	default:
+		return fmt.Sprintf("severity(%d)", int(s))
+	}
+}
+
+// Finding is a single problem reported by Configuration.Validate. Peer is the
+// index of the offending peer, or -1 when the finding is about the interface.
+type Finding struct {
+	Severity Severity
+	Check    string
+	Peer     int
+	Message  string
+}
+
+func (f Finding) String() string {
+	if f.Peer < 0 {
+		return fmt.Sprintf("%s: [%s] %s", f.Severity, f.Check, f.Message)
+	}
+
+	return fmt.Sprintf(
+		"%s: [%s] peer %d: %s",
+		f.Severity,
+		f.Check,
+		f.Peer,
+		f.Message,
+	)
+}
+
+// HasErrors reports whether any of the findings has SeverityError.
+func HasErrors(findings []Finding) bool {
+	for _, f := range findings {
+		if f.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+// Validate checks the configuration for mistakes that ToINIFormat and
+// ToIPCFormat would happily write out: malformed keys, peers that route
+// nothing or fight over the same prefixes, address families the interface
+// cannot carry, malformed or ambiguous host endpoints, DNS servers that leak
+// outside a full tunnel, keepalive intervals that are unlikely to do what was
+// intended, and AmneziaWG parameters AmneziaWG would reject.
+func (c *Configuration) Validate() []Finding {
+	var findings []Finding
+
+	if err := checkKey(c.PrivateKey); err != nil {
+		findings = append(findings, Finding{
+			Severity: SeverityError,
+			Check:    "key",
+			Peer:     -1,
+			Message:  fmt.Sprintf("private key %v", err),
+		})
+	}
+
+	for i, peer := range c.Peers {
+		findings = append(findings, c.validatePeer(i, peer)...)
+	}
+
+	findings = append(findings, c.validateAllowedIPOverlap()...)
+	findings = append(findings, c.validateDNSLeak()...)
+
+	if c.AmneziaWG != nil {
+		for _, problem := range c.AmneziaWG.Problems() {
+			findings = append(findings, Finding{
+				Severity: SeverityError,
+				Check:    "amneziawg",
+				Peer:     -1,
+				Message:  problem,
+			})
+		}
+	}
+
+	return findings
+}
+
+func (c *Configuration) validatePeer(i int, peer PeerConfig) []Finding {
+	var findings []Finding
+
+	add := func(severity Severity, check string, format string, args ...any) {
+		findings = append(findings, Finding{
+			Severity: severity,
+			Check:    check,
+			Peer:     i,
+			Message:  fmt.Sprintf(format, args...),
+		})
+	}
+
+	if err := checkKey(peer.PublicKey); err != nil {
+		add(SeverityError, "key", "public key %v", err)
+	}
+
+	if peer.PresharedKey != "" {
+		if err := checkKey(peer.PresharedKey); err != nil {
+			add(SeverityError, "key", "preshared key %v", err)
+		}
+	}
+
+	if len(peer.AllowedIPs) == 0 {
+		add(SeverityWarning, "allowed-ips", "no AllowedIPs, peer routes nothing")
+	}
+
+	for _, prefix := range peer.AllowedIPs {
+		if !c.hasAddressFamily(prefix.Addr()) {
+			add(
+				SeverityWarning,
+				"address-family",
+				"AllowedIPs %s is %s but the interface has no %s address",
+				prefix,
+				familyName(prefix.Addr()),
+				familyName(prefix.Addr()),
+			)
+		}
+	}
+
+	if peer.Endpoint.IsValid() && !c.hasAddressFamily(peer.Endpoint.Addr()) {
+		add(
+			SeverityInfo,
+			"address-family",
+			"endpoint %s is %s but the interface has no %s address",
+			peer.Endpoint,
+			familyName(peer.Endpoint.Addr()),
+			familyName(peer.Endpoint.Addr()),
+		)
+	}
+
+	switch {
+	case peer.EndpointHost == "":
+	case peer.Endpoint.IsValid():
+		add(
+			SeverityError,
+			"endpoint",
+			"both endpoint %s and host endpoint %s are set",
+			peer.Endpoint,
+			peer.EndpointHost,
+		)
+	case !isHostEndpoint(peer.EndpointHost):
+		add(
+			SeverityError,
+			"endpoint",
+			"host endpoint %q is not a host name and port",
+			peer.EndpointHost,
+		)
+	default:
+		add(
+			SeverityInfo,
+			"endpoint",
+			"endpoint %s is resolved when the interface comes up, formats "+
+				"that need an address reject it",
+			peer.EndpointHost,
+		)
+	}
+
+	keepalive := peer.PersistentKeepalive
+	outOfRange := keepalive < minRecommendedKeepalive ||
+		keepalive > maxRecommendedKeepalive
+	if keepalive > 0 && outOfRange {
+		add(
+			SeverityWarning,
+			"keepalive",
+			"PersistentKeepalive %d is outside the recommended range %d-%d",
+			keepalive,
+			minRecommendedKeepalive,
+			maxRecommendedKeepalive,
+		)
+	}
+
+	return findings
+}
+
+func (c *Configuration) validateAllowedIPOverlap() []Finding {
+	var findings []Finding
+
+	for i := range c.Peers {
+		for j := i + 1; j < len(c.Peers); j++ {
+			for _, a := range c.Peers[i].AllowedIPs {
+				for _, b := range c.Peers[j].AllowedIPs {
+					if !a.Overlaps(b) {
+						continue
+					}
+
+					severity := SeverityWarning
+					if a.Masked() == b.Masked() {
+						severity = SeverityError
+					}
+
+					findings = append(findings, Finding{
+						Severity: severity,
+						Check:    "allowed-ips-overlap",
+						Peer:     j,
+						Message: fmt.Sprintf(
+							"AllowedIPs %s overlaps %s of peer %d",
+							b,
+							a,
+							i,
+						),
+					})
+				}
+			}
+		}
+	}
+
+	return findings
+}
+
+func (c *Configuration) validateDNSLeak() []Finding {
+	if !c.IsFullTunnel() {
+		return nil
+	}
+
+	var findings []Finding
+
+	for _, dns := range c.DNS {
+		if c.routesThroughTunnel(dns) {
+			continue
+		}
+
+		findings = append(findings, Finding{
+			Severity: SeverityWarning,
+			Check:    "dns-leak",
+			Peer:     -1,
+			Message: fmt.Sprintf(
+				"DNS server %s is not routed through the full tunnel",
+				dns,
+			),
+		})
+	}
+
+	return findings
+}
+
+// IsFullTunnel reports whether any peer carries a default route, so that all
+// traffic of at least one address family goes through the tunnel.
+func (c *Configuration) IsFullTunnel() bool {
+	for _, peer := range c.Peers {
+		for _, prefix := range peer.AllowedIPs {
+			if prefix.Bits() == 0 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (c *Configuration) routesThroughTunnel(addr netip.Addr) bool {
+	for _, peer := range c.Peers {
+		for _, prefix := range peer.AllowedIPs {
+			if prefix.Contains(addr) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (c *Configuration) hasAddressFamily(addr netip.Addr) bool {
+	for _, prefix := range c.InterfaceAddresses {
+		if prefix.Addr().Is4() == addr.Unmap().Is4() {
+			return true
+		}
+	}
+	return false
+}
+
+func checkKey(key string) error {
+	if key == "" {
+		return errors.New("is empty")
+	}
+
+	decoded, err := base64.StdEncoding.DecodeString(key)
+	if err != nil {
+		return fmt.Errorf("is not valid base64: %w", err)
+	}
+
+	if len(decoded) != keyLength {
+		return fmt.Errorf(
+			"has length %d (expected %d)",
+			len(decoded),
+			keyLength,
+		)
+	}
+
+	return nil
+}
+
+func familyName(addr netip.Addr) string {
+	if addr.Unmap().Is4() {
+		return "IPv4"
+	}
+	return "IPv6"
+}
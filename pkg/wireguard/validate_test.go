@@ -0,0 +1,197 @@
+package wireguard
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/samber/lo"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConfiguration_Validate(t *testing.T) {
+	t.Parallel()
+
+	const (
+		privateKey = "OEvHuuMpALNf7ZZkzUSGbT8vkj89aHrhLyqZlIn4rPU="
+		publicKey  = "qIhtTW9K4iXWFo5Q4dOPdXg8/xubXr9yEGoN55D8xnA="
+		otherKey   = "3QnSY6ObZk8KnDrHNyT4H3dBf7sNfFMx8Yl2YpXg1W0="
+	)
+
+	validPeer := func() PeerConfig {
+		return NewPeerConfig(
+			publicKey,
+			netip.MustParseAddrPort("62.3.36.228:51820"),
+			[]netip.Prefix{netip.MustParsePrefix("0.0.0.0/0")},
+			25,
+		)
+	}
+
+	validConfig := func() Configuration {
+		return NewConfiguration(
+			privateKey,
+			[]netip.Prefix{netip.MustParsePrefix("10.5.0.2/32")},
+			[]netip.Addr{netip.MustParseAddr("103.86.96.100")},
+			[]PeerConfig{validPeer()},
+		)
+	}
+
+	tests := []struct {
+		name         string
+		mutate       func(c *Configuration)
+		wantCheck    string
+		wantSeverity Severity
+		wantPeer     int
+	}{
+		{
+			name: "private key with wrong length",
+			mutate: func(c *Configuration) {
+				c.PrivateKey = "c2hvcnQ="
+			},
+			wantCheck:    "key",
+			wantSeverity: SeverityError,
+			wantPeer:     -1,
+		},
+		{
+			name: "public key that is not base64",
+			mutate: func(c *Configuration) {
+				c.Peers[0].PublicKey = "public_key"
+			},
+			wantCheck:    "key",
+			wantSeverity: SeverityError,
+			wantPeer:     0,
+		},
+		{
+			name: "empty allowed IPs",
+			mutate: func(c *Configuration) {
+				c.Peers[0].AllowedIPs = nil
+			},
+			wantCheck:    "allowed-ips",
+			wantSeverity: SeverityWarning,
+			wantPeer:     0,
+		},
+		{
+			name: "identical allowed IPs between peers",
+			mutate: func(c *Configuration) {
+				peer := validPeer()
+				peer.PublicKey = otherKey
+				c.Peers = append(c.Peers, peer)
+			},
+			wantCheck:    "allowed-ips-overlap",
+			wantSeverity: SeverityError,
+			wantPeer:     1,
+		},
+		{
+			name: "partially overlapping allowed IPs between peers",
+			mutate: func(c *Configuration) {
+				peer := validPeer()
+				peer.PublicKey = otherKey
+				peer.AllowedIPs = []netip.Prefix{
+					netip.MustParsePrefix("10.0.0.0/8"),
+				}
+				c.Peers = append(c.Peers, peer)
+			},
+			wantCheck:    "allowed-ips-overlap",
+			wantSeverity: SeverityWarning,
+			wantPeer:     1,
+		},
+		{
+			name: "IPv6 allowed IPs without an IPv6 interface address",
+			mutate: func(c *Configuration) {
+				c.Peers[0].AllowedIPs = append(
+					c.Peers[0].AllowedIPs,
+					netip.MustParsePrefix("::/0"),
+				)
+			},
+			wantCheck:    "address-family",
+			wantSeverity: SeverityWarning,
+			wantPeer:     0,
+		},
+		{
+			name: "IPv6 endpoint without an IPv6 interface address",
+			mutate: func(c *Configuration) {
+				c.Peers[0].Endpoint = netip.MustParseAddrPort(
+					"[2a00:1450::1]:51820",
+				)
+			},
+			wantCheck:    "address-family",
+			wantSeverity: SeverityInfo,
+			wantPeer:     0,
+		},
+		{
+			name: "host endpoint",
+			mutate: func(c *Configuration) {
+				c.Peers[0].Endpoint = netip.AddrPort{}
+				c.Peers[0].EndpointHost = "vpn.example:51820"
+			},
+			wantCheck:    "endpoint",
+			wantSeverity: SeverityInfo,
+			wantPeer:     0,
+		},
+		{
+			name: "host endpoint next to an address endpoint",
+			mutate: func(c *Configuration) {
+				c.Peers[0].EndpointHost = "vpn.example:51820"
+			},
+			wantCheck:    "endpoint",
+			wantSeverity: SeverityError,
+			wantPeer:     0,
+		},
+		{
+			name: "host endpoint without a port",
+			mutate: func(c *Configuration) {
+				c.Peers[0].Endpoint = netip.AddrPort{}
+				c.Peers[0].EndpointHost = "vpn.example"
+			},
+			wantCheck:    "endpoint",
+			wantSeverity: SeverityError,
+			wantPeer:     0,
+		},
+		{
+			name: "IPv6 DNS server outside an IPv4 full tunnel",
+			mutate: func(c *Configuration) {
+				c.DNS = append(c.DNS, netip.MustParseAddr("2606:4700::1111"))
+			},
+			wantCheck:    "dns-leak",
+			wantSeverity: SeverityWarning,
+			wantPeer:     -1,
+		},
+		{
+			name: "keepalive above the recommended range",
+			mutate: func(c *Configuration) {
+				c.Peers[0].PersistentKeepalive = 3600
+			},
+			wantCheck:    "keepalive",
+			wantSeverity: SeverityWarning,
+			wantPeer:     0,
+		},
+	}
+
+	t.Run("a valid configuration has no findings", func(t *testing.T) {
+		t.Parallel()
+
+		config := validConfig()
+		assert.Empty(t, config.Validate())
+	})
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			config := validConfig()
+			tt.mutate(&config)
+
+			findings := config.Validate()
+
+			assert.True(t, lo.ContainsBy(findings, func(f Finding) bool {
+				return f.Severity == tt.wantSeverity &&
+					f.Check == tt.wantCheck &&
+					f.Peer == tt.wantPeer
+			}), "findings: %v", findings)
+			assert.Equal(
+				t,
+				tt.wantSeverity == SeverityError,
+				HasErrors(findings),
+			)
+		})
+	}
+}